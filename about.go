@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileKind is a kind of repository file --about knows how to ask about.
+type fileKind struct {
+	Name     string // e.g. "GitHub Actions workflow file"
+	Lang     string // code block language
+	Question string // asked when no question is given
+}
+
+var (
+	kindWorkflow = fileKind{
+		Name:     "GitHub Actions workflow file",
+		Lang:     "yaml",
+		Question: "Is this GitHub Actions workflow valid? Point out mistakes, deprecated syntax or actions, and anything that will not work as intended.",
+	}
+	kindAction = fileKind{
+		Name:     "GitHub Actions metadata file (action.yml)",
+		Lang:     "yaml",
+		Question: "Is this action metadata file valid? Point out mistakes, missing required keys and deprecated runtimes or syntax.",
+	}
+	kindDependabot = fileKind{
+		Name:     "Dependabot configuration file (dependabot.yml)",
+		Lang:     "yaml",
+		Question: "Is this Dependabot configuration valid? Point out mistakes, unsupported ecosystems or options, and anything that will not work as intended.",
+	}
+	kindCodeowners = fileKind{
+		Name:     "CODEOWNERS file",
+		Lang:     "gitignore",
+		Question: "Is this CODEOWNERS file valid? Point out syntax errors, patterns that do not match as intended and rules overridden by later ones.",
+	}
+	kindYAML = fileKind{
+		Name:     "GitHub configuration file",
+		Lang:     "yaml",
+		Question: "Is this GitHub configuration file valid? Point out mistakes and anything that will not work as intended.",
+	}
+	kindOther = fileKind{
+		Name:     "file",
+		Question: "Is this file valid for GitHub? Point out mistakes and anything that will not work as intended.",
+	}
+)
+
+// detectFileKind tells the kind of a file from its path.
+func detectFileKind(path string) fileKind {
+	slash := filepath.ToSlash(path)
+	base := filepath.Base(path)
+	yml := strings.EqualFold(filepath.Ext(path), ".yml") || strings.EqualFold(filepath.Ext(path), ".yaml")
+
+	switch {
+	case yml && strings.Contains(slash, ".github/workflows/"):
+		return kindWorkflow
+	case base == "action.yml" || base == "action.yaml":
+		return kindAction
+	case base == "dependabot.yml" || base == "dependabot.yaml":
+		return kindDependabot
+	case base == "CODEOWNERS":
+		return kindCodeowners
+	case yml:
+		return kindYAML
+	}
+	return kindOther
+}
+
+// applyAboutConfig picks the docs version and language for --about from the
+// config of the repository the file is in, unless they were given as flags.
+// The environment still wins over that repository's .gh-ask-docs.yml.
+func applyAboutConfig(opts *options, versionSet, languageSet bool) error {
+	abs, err := filepath.Abs(opts.about)
+	if err != nil {
+		return err
+	}
+	repoPath := repoConfigPathFrom(filepath.Dir(abs))
+	if repoPath == "" {
+		return nil
+	}
+	fromRepo, err := configuredOptionsFrom(repoPath)
+	if err != nil {
+		return err
+	}
+	if !versionSet {
+		opts.version = fromRepo.version
+	}
+	if !languageSet {
+		opts.language = fromRepo.language
+	}
+	return nil
+}
+
+// addAboutFile adds the --about file to the question, asking the default
+// question for its kind when there is none. A file that does not fit within
+// maxQuestionBytes is summarized and, if still too long, cut with a warning.
+func addAboutFile(opts *options) error {
+	data, err := os.ReadFile(opts.about)
+	if err != nil {
+		return err
+	}
+	kind := detectFileKind(opts.about)
+
+	question := opts.query
+	if question == "" {
+		question = kind.Question
+	}
+	intro := fmt.Sprintf("%s\n\nThis is my %s, %s:\n\n", question, kind.Name, filepath.ToSlash(opts.about))
+	budget := maxQuestionBytes - len(intro) - 16 // leave room for the code fence
+
+	content := strings.TrimSpace(string(data))
+	if len(content) > budget {
+		content = summarizeFile(kind, content)
+	}
+	if len(content) > budget {
+		fmt.Fprintf(os.Stderr, "⚠️  %s is %s even when summarized; only the first %s is sent\n", opts.about, formatBytes(len(content)), formatBytes(max(budget, 0)))
+		content = cutLines(content, budget)
+	}
+	opts.query = intro + fenced(content, kind.Lang)
+	return nil
+}
+
+// summarizeFile shortens a file while keeping its structure: YAML loses its
+// comments and every multi-line value (such as a run script) is cut to its
+// first line; CODEOWNERS loses comments and blank lines. Invalid YAML is
+// returned as is, since that is what needs explaining.
+func summarizeFile(kind fileKind, content string) string {
+	if kind == kindCodeowners {
+		var lines []string
+		for _, l := range strings.Split(content, "\n") {
+			if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "#") {
+				lines = append(lines, l)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	if kind.Lang != "yaml" {
+		return content
+	}
+
+	var doc yaml.Node
+	if yaml.Unmarshal([]byte(content), &doc) != nil {
+		return content
+	}
+	summarizeNode(&doc)
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if enc.Encode(&doc) != nil || enc.Close() != nil {
+		return content
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// summarizeNode drops comments and cuts multi-line scalars below n.
+func summarizeNode(n *yaml.Node) {
+	n.HeadComment, n.LineComment, n.FootComment = "", "", ""
+	if n.Kind == yaml.ScalarNode {
+		if first, rest, ok := strings.Cut(strings.TrimSpace(n.Value), "\n"); ok {
+			n.Value = fmt.Sprintf("%s … (%d more lines)", first, strings.Count(rest, "\n")+1)
+			n.Style = 0
+		}
+	}
+	for _, c := range n.Content {
+		summarizeNode(c)
+	}
+}
+
+// cutLines cuts s to at most n bytes at a line boundary.
+func cutLines(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	n = max(n, 0)
+	if i := strings.LastIndexByte(s[:n], '\n'); i >= 0 {
+		return s[:i]
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectFileKind(t *testing.T) {
+	tests := []struct {
+		path string
+		want fileKind
+	}{
+		{".github/workflows/ci.yml", kindWorkflow},
+		{"/src/app/.github/workflows/release.YAML", kindWorkflow},
+		{"action.yml", kindAction},
+		{"actions/setup/action.yaml", kindAction},
+		{".github/dependabot.yml", kindDependabot},
+		{".github/CODEOWNERS", kindCodeowners},
+		{"docs/CODEOWNERS", kindCodeowners},
+		{".github/release.yml", kindYAML},
+		{"README.md", kindOther},
+	}
+	for _, tt := range tests {
+		if got := detectFileKind(tt.path); got != tt.want {
+			t.Errorf("detectFileKind(%q) = %q, want %q", tt.path, got.Name, tt.want.Name)
+		}
+	}
+}
+
+func TestAddAboutFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".github", "dependabot.yml")
+	writeFile(t, path, "version: 2\nupdates:\n  - package-ecosystem: npm\n    directory: /\n")
+
+	opts := defaultOptions()
+	opts.about = path
+	if err := addAboutFile(&opts); err != nil {
+		t.Fatalf("addAboutFile() error: %v", err)
+	}
+	if !strings.HasPrefix(opts.query, kindDependabot.Question) {
+		t.Errorf("query = %q, want the default Dependabot question", opts.query)
+	}
+	if !strings.HasSuffix(opts.query, "```yaml\nversion: 2\nupdates:\n  - package-ecosystem: npm\n    directory: /\n```") {
+		t.Errorf("query = %q, want the whole file", opts.query)
+	}
+
+	opts = defaultOptions()
+	opts.about = path
+	opts.query = "Can I group updates?"
+	if err := addAboutFile(&opts); err != nil {
+		t.Fatalf("addAboutFile() error: %v", err)
+	}
+	if !strings.HasPrefix(opts.query, "Can I group updates?\n\nThis is my Dependabot configuration file") {
+		t.Errorf("query = %q, want the given question first", opts.query)
+	}
+
+	opts.about = filepath.Join(dir, "missing.yml")
+	if err := addAboutFile(&opts); err == nil {
+		t.Error("addAboutFile() of a missing file should fail")
+	}
+}
+
+func TestAddAboutFileSummarizes(t *testing.T) {
+	script := strings.Repeat("  echo building\n", maxQuestionBytes/16)
+	workflow := "# Build on every push\nname: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest # the cheapest\n    steps:\n      - uses: actions/checkout@v4\n      - run: |\n" + strings.ReplaceAll(script, "  echo", "          echo")
+	path := filepath.Join(t.TempDir(), ".github", "workflows", "ci.yml")
+	writeFile(t, path, workflow)
+
+	opts := defaultOptions()
+	opts.about = path
+	stderr := captureStderr(t, func() {
+		if err := addAboutFile(&opts); err != nil {
+			t.Errorf("addAboutFile() error: %v", err)
+		}
+	})
+	if stderr != "" {
+		t.Errorf("warning = %q, want none once summarized", stderr)
+	}
+	want := "```yaml\nname: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n      - run: echo building … ("
+	if !strings.Contains(opts.query, want) || strings.Contains(opts.query, "cheapest") {
+		t.Errorf("query = %.400q, want the summarized workflow", opts.query)
+	}
+}
+
+func TestAddAboutFileTruncates(t *testing.T) {
+	var codeowners strings.Builder
+	for i := 0; codeowners.Len() < 2*maxQuestionBytes; i++ {
+		codeowners.WriteString("# team\n/src/module" + strings.Repeat("x", i%7) + "/ @octo/team\n")
+	}
+	path := filepath.Join(t.TempDir(), "CODEOWNERS")
+	writeFile(t, path, codeowners.String())
+
+	opts := defaultOptions()
+	opts.about = path
+	stderr := captureStderr(t, func() {
+		if err := addAboutFile(&opts); err != nil {
+			t.Errorf("addAboutFile() error: %v", err)
+		}
+	})
+	if !strings.Contains(stderr, "even when summarized") {
+		t.Errorf("warning = %q", stderr)
+	}
+	if len(opts.query) > maxQuestionBytes || strings.Contains(opts.query, "# team") || !strings.HasSuffix(opts.query, "@octo/team\n```") {
+		t.Errorf("query is %d bytes ending %q, want comments dropped and whole lines within the limit", len(opts.query), opts.query[len(opts.query)-30:])
+	}
+}
+
+func TestApplyAboutConfig(t *testing.T) {
+	withConfigDirs(t)
+
+	// The file is in another repository with its own config.
+	other := t.TempDir()
+	if err := os.Mkdir(filepath.Join(other, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(other, repoConfigName), "version: enterprise-server@3.19\nlanguage: ja\n")
+	path := filepath.Join(other, ".github", "workflows", "ci.yml")
+	writeFile(t, path, "on: push\n")
+
+	opts, err := parseArgs(defaultOptions(), []string{"--about", path})
+	if err != nil {
+		t.Fatalf("parseArgs() error: %v", err)
+	}
+	if opts.version != "enterprise-server@3.19" || opts.language != "ja" {
+		t.Errorf("version = %q, language = %q; want them from the file's repository", opts.version, opts.language)
+	}
+
+	opts, err = parseArgs(defaultOptions(), []string{"--about", path, "--version", "enterprise-cloud"})
+	if err != nil {
+		t.Fatalf("parseArgs() error: %v", err)
+	}
+	if opts.version != "enterprise-cloud" || opts.language != "ja" {
+		t.Errorf("version = %q, language = %q; --version should win", opts.version, opts.language)
+	}
+
+	t.Setenv("GH_ASK_DOCS_VERSION", "free-pro-team")
+	opts, _ = parseArgs(defaultOptions(), []string{"--about", path})
+	if opts.version != "free-pro-team" {
+		t.Errorf("version = %q, the environment should win over the repository config", opts.version)
+	}
+}
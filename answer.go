@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/glamour"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// renderers holds the Glamour renderers used for answers and sources.
+type renderers struct {
+	answer *glamour.TermRenderer
+	noWrap *glamour.TermRenderer
+}
+
+// newRenderers builds the renderers for the given theme and wrap width.
+func newRenderers(theme string, wrapWidth int) (renderers, error) {
+	var r renderers
+
+	switch theme {
+	case "auto":
+		// Try auto-detection first, fall back to manual detection if needed
+		r.answer = askdocs.NewAutoRenderer(wrapWidth)
+		r.noWrap = askdocs.NewAutoRenderer(0)
+
+		// If auto-detection fails, fall back to our improved theme detection
+		if r.answer == nil {
+			themeDetected := "dark"
+			if askdocs.IsLight() {
+				themeDetected = "light"
+			}
+			r.answer = askdocs.NewRenderer(themeDetected, wrapWidth)
+			r.noWrap = askdocs.NewRenderer(themeDetected, 0)
+		}
+	case "light", "dark":
+		// User explicitly specified theme
+		r.answer = askdocs.NewRenderer(theme, wrapWidth)
+		r.noWrap = askdocs.NewRenderer(theme, 0)
+	default:
+		return r, fmt.Errorf("invalid theme '%s'. Use 'auto', 'light', or 'dark'", theme)
+	}
+	return r, nil
+}
+
+// partialMarker is appended to answers that stopped before they finished.
+func partialMarker(err error) string {
+	reason := "Stream interrupted"
+	switch {
+	case errors.Is(err, context.Canceled):
+		reason = "Interrupted"
+	case errors.Is(err, context.DeadlineExceeded):
+		reason = "Timed out"
+	}
+	return "\n\n---\n\n⚠️ *" + reason + ": this answer is incomplete.*\n"
+}
+
+// Output formats accepted by --format.
+const (
+	formatText   = "text"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// streamAnswer asks the question and writes the answer to stdout as it
+// streams, following the format / raw / no-stream settings in opts. The
+// returned result is populated as far as the stream got, even on error.
+// When ctx is cancelled (Ctrl-C or --timeout), or the stream is interrupted
+// with opts.keepPartial set, what was received so far is printed with a
+// partialMarker and the terminal is left clean before the error is returned.
+func streamAnswer(ctx context.Context, client askdocs.Asker, q askdocs.Query, opts options, r renderers) (*askdocs.Result, error) {
+	res := &askdocs.Result{
+		Query:    q.Query,
+		Version:  q.Version,
+		Language: q.Language,
+		Sources:  []askdocs.Source{},
+		Timings:  askdocs.Timings{StartedAt: time.Now()},
+	}
+	defer func() {
+		res.Timings.TotalMS = time.Since(res.Timings.StartedAt).Milliseconds()
+	}()
+
+	stream, err := client.Ask(ctx, q)
+	if err != nil {
+		return res, err
+	}
+	defer stream.Close()
+
+	text := opts.format == formatText
+	enc := json.NewEncoder(os.Stdout)
+
+	var (
+		buf       strings.Builder
+		prevLines int
+		spinIdx   int
+		streamErr error
+	)
+
+	for {
+		ev, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			keep := ctx.Err() != nil || (opts.keepPartial && errors.Is(err, askdocs.ErrStreamInterrupted))
+			if !keep {
+				return res, err
+			}
+			streamErr = err
+			res.Partial = true
+			break
+		}
+
+		if opts.format == formatNDJSON {
+			_ = enc.Encode(ev.Line())
+		}
+
+		switch ev.Type {
+		case askdocs.ChunkMessage:
+			if buf.Len() == 0 {
+				res.Timings.FirstChunkMS = time.Since(res.Timings.StartedAt).Milliseconds()
+			}
+			buf.WriteString(ev.Text)
+			res.Answer = buf.String()
+			if text && opts.raw && !opts.noStream {
+				fmt.Print(ev.Text)
+			}
+
+		case askdocs.ChunkSources:
+			res.Sources = stream.Sources()
+
+		case askdocs.ChunkConversationID:
+			res.ConversationID = ev.ConversationID
+
+		case askdocs.ChunkNoContent:
+			return res, askdocs.ErrNoContent
+
+		case askdocs.ChunkInputFilter:
+			return res, askdocs.ErrContentFiltered
+		}
+
+		//--------------------------------------------------------------
+		// Frame / Spinner
+		//--------------------------------------------------------------
+		if !text {
+			continue
+		}
+
+		if opts.noStream {
+			askdocs.RenderSpinner(askdocs.SpinnerFrames[spinIdx%len(askdocs.SpinnerFrames)])
+			spinIdx++
+			continue
+		}
+
+		if !opts.raw {
+			askdocs.RenderFrame(r.answer, buf.String(), askdocs.SpinnerFrames[spinIdx%len(askdocs.SpinnerFrames)], &prevLines)
+			spinIdx++
+		}
+	}
+
+	if !text {
+		return res, streamErr
+	}
+
+	if res.Partial {
+		marker := partialMarker(streamErr)
+		buf.WriteString(marker)
+		if opts.raw && !opts.noStream {
+			fmt.Print(marker)
+		}
+	}
+
+	//----------------------------------------------------------------------
+	// Clear spinner / final repaint
+	//----------------------------------------------------------------------
+	if opts.noStream {
+		fmt.Fprint(os.Stderr, "\r \r")
+	} else if !opts.raw {
+		askdocs.RenderFrame(r.answer, buf.String(), ' ', &prevLines)
+		fmt.Println()
+	}
+
+	//----------------------------------------------------------------------
+	// Output buffered answer (no-stream mode)
+	//----------------------------------------------------------------------
+	if opts.noStream {
+		if opts.raw {
+			fmt.Print(buf.String())
+		} else {
+			out, _ := r.answer.Render(buf.String())
+			fmt.Print(out)
+		}
+		fmt.Println()
+	}
+
+	return res, streamErr
+}
+
+// collectAnswer asks the question and returns the complete answer without
+// printing anything.
+func collectAnswer(ctx context.Context, client askdocs.Asker, q askdocs.Query) (*askdocs.Result, error) {
+	res := &askdocs.Result{
+		Query:    q.Query,
+		Version:  q.Version,
+		Language: q.Language,
+		Sources:  []askdocs.Source{},
+		Timings:  askdocs.Timings{StartedAt: time.Now()},
+	}
+	defer func() {
+		res.Timings.TotalMS = time.Since(res.Timings.StartedAt).Milliseconds()
+	}()
+
+	stream, err := client.Ask(ctx, q)
+	if err != nil {
+		return res, err
+	}
+	defer stream.Close()
+
+	var buf strings.Builder
+	for {
+		ev, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			res.Answer = buf.String()
+			return res, err
+		}
+
+		switch ev.Type {
+		case askdocs.ChunkMessage:
+			if buf.Len() == 0 {
+				res.Timings.FirstChunkMS = time.Since(res.Timings.StartedAt).Milliseconds()
+			}
+			buf.WriteString(ev.Text)
+		case askdocs.ChunkSources:
+			res.Sources = stream.Sources()
+		case askdocs.ChunkConversationID:
+			res.ConversationID = ev.ConversationID
+		case askdocs.ChunkNoContent:
+			return res, askdocs.ErrNoContent
+		case askdocs.ChunkInputFilter:
+			return res, askdocs.ErrContentFiltered
+		}
+	}
+	res.Answer = buf.String()
+	return res, nil
+}
+
+// writeCachedAnswer prints an answer that was not streamed: rendered for
+// text, or replayed as NDJSON events. JSON is left to writeJSONResult.
+func writeCachedAnswer(res *askdocs.Result, opts options, r renderers) {
+	switch opts.format {
+	case formatNDJSON:
+		enc := json.NewEncoder(os.Stdout)
+		if res.ConversationID != "" {
+			_ = enc.Encode(askdocs.Event{Type: askdocs.ChunkConversationID, ConversationID: res.ConversationID}.Line())
+		}
+		if len(res.Sources) > 0 {
+			_ = enc.Encode(askdocs.Event{Type: askdocs.ChunkSources, Sources: res.Sources}.Line())
+		}
+		_ = enc.Encode(askdocs.Event{Type: askdocs.ChunkMessage, Text: res.Answer}.Line())
+
+	case formatText:
+		if opts.raw {
+			fmt.Print(res.Answer)
+		} else {
+			out, _ := r.answer.Render(res.Answer)
+			fmt.Print(out)
+		}
+		fmt.Println()
+	}
+}
+
+// writeJSONResult prints the result as a single indented JSON object.
+func writeJSONResult(res *askdocs.Result) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(res)
+}
+
+// printSources writes the reference links for an answer.
+func printSources(sources []askdocs.Source, raw bool, r renderers) {
+	if len(sources) == 0 {
+		return
+	}
+
+	if raw {
+		fmt.Println("\nSources:")
+		for _, s := range sources {
+			if s.Title != "" {
+				fmt.Printf("- %s (%s)\n", s.Title, s.URL)
+			} else {
+				fmt.Printf("- %s\n", s.URL)
+			}
+		}
+		return
+	}
+
+	var md strings.Builder
+	md.WriteString("### Sources\n")
+	for _, s := range sources {
+		text := s.Title
+		if text == "" {
+			text = s.URL
+		}
+		md.WriteString(fmt.Sprintf("* %s\n", askdocs.AutoLink(s.URL, text)))
+	}
+	out, _ := r.noWrap.Render(md.String())
+	fmt.Print(out)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// captureStdout returns everything written to stdout while fn runs.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	orig := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+
+	w.Close()
+	os.Stdout = orig
+	return <-done
+}
+
+// forkAnswer is a complete answer with one source, for tests that need an
+// answer but not a particular one.
+var forkAnswer = []string{
+	`{"chunkType":"SOURCES","sources":[{"title":"Forks","url":"https://docs.github.com/forks"}]}`,
+	`{"chunkType":"MESSAGE_CHUNK","text":"Use forks."}`,
+}
+
+// newSearchServer starts a fake AI Search API that answers every question
+// with lines and counts the requests it receives. reply, if set, is called
+// with each request's payload instead and returns the status and lines to
+// answer with.
+func newSearchServer(t *testing.T, lines []string, reply func(payload map[string]string) (int, []string)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		status, out := http.StatusOK, lines
+		if reply != nil {
+			var payload map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			status, out = reply(payload)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(status)
+		for _, l := range out {
+			_, _ = w.Write([]byte(l + "\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+// newSearchClient returns a client of a fake AI Search API that answers
+// every question with lines.
+func newSearchClient(t *testing.T, lines ...string) *askdocs.Client {
+	t.Helper()
+	server, _ := newSearchServer(t, lines, nil)
+	client := askdocs.NewClient()
+	client.Endpoint = server.URL
+	return client
+}
+
+func TestStreamAnswerResult(t *testing.T) {
+	client := newSearchClient(t,
+		`{"chunkType":"CONVERSATION_ID","conversation_id":"conv-1"}`,
+		`{"chunkType":"MESSAGE_CHUNK","text":"Use "}`,
+		`{"chunkType":"MESSAGE_CHUNK","text":"forks."}`,
+		`{"chunkType":"SOURCES","sources":[{"title":"Fork","url":"https://docs.github.com/fork"},{"title":"Fork","url":"https://docs.github.com/fork"}]}`,
+	)
+
+	var res *askdocs.Result
+	out := captureStdout(t, func() {
+		var err error
+		res, err = streamAnswer(context.Background(), client, askdocs.Query{Query: "q", Version: "free-pro-team@latest"}, options{format: formatJSON}, renderers{})
+		if err != nil {
+			t.Errorf("streamAnswer() error: %v", err)
+		}
+	})
+
+	if out != "" {
+		t.Errorf("json format should not write while streaming, got %q", out)
+	}
+	if res.Query != "q" || res.Version != "free-pro-team@latest" {
+		t.Errorf("query/version = %q/%q", res.Query, res.Version)
+	}
+	if res.Answer != "Use forks." {
+		t.Errorf("Answer = %q, want %q", res.Answer, "Use forks.")
+	}
+	if len(res.Sources) != 1 {
+		t.Errorf("Sources = %+v, want one de-duplicated source", res.Sources)
+	}
+	if res.ConversationID != "conv-1" {
+		t.Errorf("ConversationID = %q, want %q", res.ConversationID, "conv-1")
+	}
+	if res.Timings.StartedAt.IsZero() {
+		t.Error("Timings.StartedAt should be set")
+	}
+}
+
+func TestStreamAnswerNDJSON(t *testing.T) {
+	client := newSearchClient(t,
+		`{"chunkType":"MESSAGE_CHUNK","text":"Hi","extra":"dropped"}`,
+		`not json`,
+		`{"chunkType":"SOURCES","sources":[{"title":"Docs","url":"https://docs.github.com","score":1}]}`,
+	)
+
+	out := captureStdout(t, func() {
+		if _, err := streamAnswer(context.Background(), client, askdocs.Query{Query: "q"}, options{format: formatNDJSON}, renderers{}); err != nil {
+			t.Errorf("streamAnswer() error: %v", err)
+		}
+	})
+
+	want := `{"chunkType":"MESSAGE_CHUNK","text":"Hi"}` + "\n" +
+		`{"chunkType":"SOURCES","sources":[{"title":"Docs","url":"https://docs.github.com"}]}` + "\n"
+	if out != want {
+		t.Errorf("ndjson output = %q, want %q", out, want)
+	}
+}
+
+func TestStreamAnswerNoContent(t *testing.T) {
+	client := newSearchClient(t,
+		`{"chunkType":"MESSAGE_CHUNK","text":"partial"}`,
+		`{"chunkType":"NO_CONTENT_SIGNAL"}`,
+	)
+
+	var res *askdocs.Result
+	var err error
+	captureStdout(t, func() {
+		res, err = streamAnswer(context.Background(), client, askdocs.Query{Query: "q"}, options{format: formatJSON}, renderers{})
+	})
+
+	if !errors.Is(err, askdocs.ErrNoContent) {
+		t.Fatalf("err = %v, want ErrNoContent", err)
+	}
+	if res == nil || res.Answer != "partial" {
+		t.Errorf("result should hold the partial answer, got %+v", res)
+	}
+
+	res.Error = err.Error()
+	data, _ := json.Marshal(res)
+	if !strings.Contains(string(data), `"error":"the AI could not answer your question"`) {
+		t.Errorf("JSON should include the error: %s", data)
+	}
+}
+
+func TestStreamAnswerContentFiltered(t *testing.T) {
+	client := newSearchClient(t, `{"chunkType":"INPUT_CONTENT_FILTER"}`)
+
+	var err error
+	captureStdout(t, func() {
+		_, err = streamAnswer(context.Background(), client, askdocs.Query{Query: "q"}, options{format: formatJSON}, renderers{})
+	})
+
+	if !errors.Is(err, askdocs.ErrContentFiltered) {
+		t.Errorf("err = %v, want ErrContentFiltered", err)
+	}
+}
+
+// newInterruptedClient returns a client whose server aborts after one chunk.
+func newInterruptedClient(t *testing.T) *askdocs.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"chunkType":"MESSAGE_CHUNK","text":"Half an "}` + "\n"))
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+	t.Cleanup(server.Close)
+
+	client := askdocs.NewClient()
+	client.Endpoint = server.URL
+	return client
+}
+
+func TestStreamAnswerInterrupted(t *testing.T) {
+	tests := []struct {
+		name        string
+		keepPartial bool
+		wantOutput  string
+	}{
+		{"discard partial", false, "Half an "},
+		{"keep partial", true, "Half an " + partialMarker(askdocs.ErrStreamInterrupted)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newInterruptedClient(t)
+			opts := options{format: formatText, raw: true, keepPartial: tt.keepPartial}
+
+			var res *askdocs.Result
+			var err error
+			out := captureStdout(t, func() {
+				res, err = streamAnswer(context.Background(), client, askdocs.Query{Query: "q"}, opts, renderers{})
+			})
+
+			if !errors.Is(err, askdocs.ErrStreamInterrupted) {
+				t.Fatalf("err = %v, want ErrStreamInterrupted", err)
+			}
+			if res.Partial != tt.keepPartial {
+				t.Errorf("Partial = %v, want %v", res.Partial, tt.keepPartial)
+			}
+			if res.Answer != "Half an " {
+				t.Errorf("Answer = %q, want the partial text without marker", res.Answer)
+			}
+			if out != tt.wantOutput {
+				t.Errorf("output = %q, want %q", out, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestStreamAnswerTimeoutKeepsPartial(t *testing.T) {
+	stop := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"chunkType":"MESSAGE_CHUNK","text":"So far"}` + "\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-stop:
+		}
+	}))
+	defer server.Close()
+	defer close(stop)
+
+	client := askdocs.NewClient()
+	client.Endpoint = server.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var res *askdocs.Result
+	var err error
+	out := captureStdout(t, func() {
+		res, err = streamAnswer(ctx, client, askdocs.Query{Query: "q"}, options{format: formatText, raw: true}, renderers{})
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if !res.Partial {
+		t.Error("a timed out answer should be kept as partial")
+	}
+	if want := "So far" + partialMarker(err); out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if !strings.Contains(out, "Timed out") {
+		t.Errorf("marker should mention the timeout: %q", out)
+	}
+}
+
+func TestPartialMarker(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{askdocs.ErrStreamInterrupted, "Stream interrupted"},
+		{context.Canceled, "Interrupted"},
+		{context.DeadlineExceeded, "Timed out"},
+	}
+	for _, tt := range tests {
+		if got := partialMarker(tt.err); !strings.Contains(got, tt.want+": this answer is incomplete.") {
+			t.Errorf("partialMarker(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
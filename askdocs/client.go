@@ -0,0 +1,378 @@
+package askdocs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// DefaultEndpoint is the docs.github.com AI Search API endpoint.
+const DefaultEndpoint = "https://docs.github.com/api/ai-search/v1"
+
+// DefaultClientName is sent as "client_name" in every request payload.
+const DefaultClientName = "gh-ask-docs"
+
+// Query is a single question sent to the AI Search API.
+type Query struct {
+	Query    string
+	Version  string // normalized version, e.g. "free-pro-team@latest"
+	Language string // defaults to DefaultLanguage; source links are localized to it
+
+	// ConversationID continues an earlier conversation so follow-up
+	// questions are answered in context.
+	ConversationID string
+}
+
+// Client talks to the docs.github.com AI Search API.
+type Client struct {
+	Endpoint   string
+	ClientName string
+	HTTPClient *http.Client
+
+	// Header is added to every request, e.g. for a proxy's auth token.
+	// Values set here replace the default Content-Type and Accept headers.
+	Header http.Header
+
+	// Debug, when non-nil, receives every raw NDJSON line as it is read.
+	Debug io.Writer
+
+	// Retries is how many times a request is retried after a connection
+	// error, 429 or 5xx response. RetryBackoff is the delay before the first
+	// retry; it doubles on every attempt and is jittered. A Retry-After header
+	// takes precedence over the computed delay.
+	Retries      int
+	RetryBackoff time.Duration
+
+	// IdleTimeout cancels a request when no data (response headers or NDJSON
+	// lines) arrives for this long. Zero disables it.
+	IdleTimeout time.Duration
+
+	// OnRetry, when non-nil, is called before waiting to retry.
+	OnRetry func(attempt int, delay time.Duration, err error)
+
+	// sleep is swapped out in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Retry and timeout defaults
+const (
+	DefaultIdleTimeout  = 60 * time.Second
+	DefaultRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff     = 30 * time.Second
+	maxRetryAfter       = 2 * time.Minute
+)
+
+// NewClient returns a Client for the public docs.github.com endpoint.
+func NewClient() *Client {
+	return &Client{
+		Endpoint:     DefaultEndpoint,
+		ClientName:   DefaultClientName,
+		HTTPClient:   &http.Client{Timeout: 0},
+		IdleTimeout:  DefaultIdleTimeout,
+		RetryBackoff: DefaultRetryBackoff,
+	}
+}
+
+// Ask sends the query and returns a Stream of the NDJSON response.
+// The caller must Close the stream.
+func (c *Client) Ask(ctx context.Context, q Query) (*Stream, error) {
+	language := q.Language
+	if language == "" {
+		language = DefaultLanguage
+	}
+
+	body := map[string]string{
+		"query":       q.Query,
+		"version":     q.Version,
+		"language":    language,
+		"client_name": c.ClientName,
+	}
+	if q.ConversationID != "" {
+		body["conversation_id"] = q.ConversationID
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		stream, err := c.attempt(ctx, payload)
+		if err == nil {
+			stream.language = language
+			return stream, nil
+		}
+		if attempt >= c.Retries || !IsRetryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		delay := c.retryDelay(attempt, err)
+		if delay > maxRetryAfter {
+			return nil, err
+		}
+		if c.OnRetry != nil {
+			c.OnRetry(attempt+1, delay, err)
+		}
+
+		sleep := c.sleep
+		if sleep == nil {
+			sleep = sleepContext
+		}
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends the request once, watched by the idle timer.
+func (c *Client) attempt(ctx context.Context, payload []byte) (*Stream, error) {
+	reqCtx, cancel := context.WithCancelCause(ctx)
+	idle := startIdleTimer(c.IdleTimeout, cancel)
+
+	resp, err := c.post(reqCtx, payload)
+	if err != nil {
+		idle.stop()
+		if cause := context.Cause(reqCtx); errors.Is(cause, ErrIdleTimeout) {
+			err = fmt.Errorf("%w: %w", ErrRequestFailed, cause)
+		}
+		cancel(nil)
+		return nil, err
+	}
+
+	s := newStream(resp.Body, c.Debug)
+	s.ctx, s.cancel, s.idle = reqCtx, cancel, idle
+	return s, nil
+}
+
+// post sends a single request and returns the response if it is a 200.
+func (c *Client) post(ctx context.Context, payload []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/x-ndjson")
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRequestFailed, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4*maxBodyExcerpt))
+		return nil, &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       bodyExcerpt(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return resp, nil
+}
+
+// retryDelay returns how long to wait before retry number attempt+1:
+// exponential backoff with jitter, or the server's Retry-After if given.
+func (c *Client) retryDelay(attempt int, err error) time.Duration {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+
+	delay := c.RetryBackoff << attempt
+	if delay <= 0 || delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	// Equal jitter: half fixed, half random
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// idleTimer cancels a request when it is not reset within d.
+type idleTimer struct {
+	d time.Duration
+	t *time.Timer
+}
+
+func startIdleTimer(d time.Duration, cancel context.CancelCauseFunc) *idleTimer {
+	if d <= 0 {
+		return nil
+	}
+	return &idleTimer{d: d, t: time.AfterFunc(d, func() {
+		cancel(fmt.Errorf("%w (%s)", ErrIdleTimeout, d))
+	})}
+}
+
+func (t *idleTimer) reset() {
+	if t != nil {
+		t.t.Reset(t.d)
+	}
+}
+
+func (t *idleTimer) stop() {
+	if t != nil {
+		t.t.Stop()
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Event is a single decoded line of the NDJSON response. Type is one of the
+// Chunk* constants; only the fields relevant to that type are populated.
+type Event struct {
+	Type           string
+	Text           string
+	Sources        []Source
+	ConversationID string
+}
+
+// Stream reads Events from an AI Search response body.
+type Stream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	debug  io.Writer
+	err    error
+
+	// Request context, its cancel func and idle timer; nil in tests that
+	// build a Stream directly.
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	idle   *idleTimer
+
+	conversationID string
+
+	// language that source links are rewritten to; empty leaves them as sent
+	language string
+
+	// Source collection
+	seen  map[string]Source
+	order []string
+}
+
+func newStream(body io.ReadCloser, debug io.Writer) *Stream {
+	return &Stream{
+		body:   body,
+		reader: bufio.NewReader(body),
+		debug:  debug,
+		seen:   map[string]Source{},
+	}
+}
+
+// Next returns the next event in the stream. Blank and malformed lines are
+// skipped. It returns io.EOF once the response has been fully read, or an
+// error wrapping ErrStreamInterrupted if reading fails part way through.
+func (s *Stream) Next() (Event, error) {
+	for {
+		if s.err != nil {
+			return Event{}, s.err
+		}
+
+		line, err := s.reader.ReadBytes('\n')
+		s.idle.reset()
+		switch {
+		case err == io.EOF:
+			s.idle.stop()
+			s.err = io.EOF
+		case err != nil:
+			s.idle.stop()
+			// Report why the request was cancelled rather than the read error
+			if s.ctx != nil {
+				if cause := context.Cause(s.ctx); cause != nil {
+					err = cause
+				}
+			}
+			s.err = fmt.Errorf("%w: %w", ErrStreamInterrupted, err)
+		}
+
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 {
+			continue
+		}
+		if s.debug != nil {
+			fmt.Fprintf(s.debug, "%s\n", trimmed)
+		}
+
+		var jl GenericLine
+		if json.Unmarshal(trimmed, &jl) != nil {
+			continue
+		}
+
+		ev := Event{
+			Type:           jl.ChunkType,
+			Text:           jl.Text,
+			ConversationID: jl.ConversationID,
+		}
+		switch jl.ChunkType {
+		case ChunkConversationID:
+			s.conversationID = jl.ConversationID
+		case ChunkSources:
+			var srcs []Source
+			if json.Unmarshal(jl.Sources, &srcs) == nil {
+				if s.language != "" {
+					for i := range srcs {
+						srcs[i].URL = LocalizeURL(srcs[i].URL, s.language)
+					}
+				}
+				ev.Sources = srcs
+				s.addSources(srcs)
+			}
+		}
+		return ev, nil
+	}
+}
+
+func (s *Stream) addSources(srcs []Source) {
+	for _, src := range srcs {
+		if _, ok := s.seen[src.URL]; !ok {
+			s.seen[src.URL] = src
+			s.order = append(s.order, src.URL)
+		}
+	}
+}
+
+// Sources returns every source seen so far, de-duplicated by URL in the
+// order they first appeared.
+func (s *Stream) Sources() []Source {
+	out := make([]Source, 0, len(s.order))
+	for _, u := range s.order {
+		out = append(out, s.seen[u])
+	}
+	return out
+}
+
+// ConversationID returns the conversation ID sent by the server, if any.
+func (s *Stream) ConversationID() string {
+	return s.conversationID
+}
+
+// Close releases the underlying response body.
+func (s *Stream) Close() error {
+	s.idle.stop()
+	if s.cancel != nil {
+		defer s.cancel(nil)
+	}
+	return s.body.Close()
+}
@@ -0,0 +1,594 @@
+package askdocs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newNDJSONServer returns a test server that replies with the given lines.
+func newNDJSONServer(t *testing.T, lines []string, check func(r *http.Request, payload map[string]string)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]string
+		_ = json.Unmarshal(body, &payload)
+		if check != nil {
+			check(r, payload)
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		for _, l := range lines {
+			_, _ = w.Write([]byte(l + "\n"))
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(url string) *Client {
+	c := NewClient()
+	c.Endpoint = url
+	return c
+}
+
+func collectEvents(t *testing.T, s *Stream) []Event {
+	t.Helper()
+	var events []Event
+	for {
+		ev, err := s.Next()
+		if err == io.EOF {
+			return events
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		events = append(events, ev)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	c := NewClient()
+	if c.Endpoint != DefaultEndpoint {
+		t.Errorf("Endpoint = %q, want %q", c.Endpoint, DefaultEndpoint)
+	}
+	if c.ClientName != DefaultClientName {
+		t.Errorf("ClientName = %q, want %q", c.ClientName, DefaultClientName)
+	}
+	if c.HTTPClient == nil {
+		t.Error("HTTPClient should not be nil")
+	}
+}
+
+func TestClientAskRequest(t *testing.T) {
+	server := newNDJSONServer(t, nil, func(r *http.Request, payload map[string]string) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q", got)
+		}
+		if got := r.Header.Get("Accept"); got != "application/x-ndjson" {
+			t.Errorf("Accept = %q", got)
+		}
+
+		want := map[string]string{
+			"query":       "What is GitHub?",
+			"version":     "enterprise-cloud@latest",
+			"language":    "en",
+			"client_name": DefaultClientName,
+		}
+		for k, v := range want {
+			if payload[k] != v {
+				t.Errorf("payload[%q] = %q, want %q", k, payload[k], v)
+			}
+		}
+	})
+
+	s, err := newTestClient(server.URL).Ask(context.Background(), Query{
+		Query:   "What is GitHub?",
+		Version: "enterprise-cloud@latest",
+	})
+	if err != nil {
+		t.Fatalf("Ask() error: %v", err)
+	}
+	defer s.Close()
+
+	if events := collectEvents(t, s); len(events) != 0 {
+		t.Errorf("expected no events, got %d", len(events))
+	}
+}
+
+func TestClientCustomHeadersAndClientName(t *testing.T) {
+	server := newNDJSONServer(t, nil, func(r *http.Request, payload map[string]string) {
+		if got := r.Header.Get("X-Token"); got != "abc" {
+			t.Errorf("X-Token = %q, want %q", got, "abc")
+		}
+		if got := r.Header.Get("Accept"); got != "application/x-ndjson" {
+			t.Errorf("Accept = %q, default headers should be kept", got)
+		}
+		if got := payload["client_name"]; got != "support-bot" {
+			t.Errorf("client_name = %q, want %q", got, "support-bot")
+		}
+	})
+
+	c := newTestClient(server.URL)
+	c.ClientName = "support-bot"
+	c.Header = http.Header{"X-Token": {"abc"}}
+
+	s, err := c.Ask(context.Background(), Query{Query: "q"})
+	if err != nil {
+		t.Fatalf("Ask() error: %v", err)
+	}
+	s.Close()
+}
+
+func TestClientAskConversationID(t *testing.T) {
+	tests := []struct {
+		name           string
+		conversationID string
+	}{
+		{"new conversation", ""},
+		{"follow-up", "conv-123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newNDJSONServer(t, []string{
+				`{"chunkType":"CONVERSATION_ID","conversation_id":"conv-123"}`,
+			}, func(r *http.Request, payload map[string]string) {
+				got, ok := payload["conversation_id"]
+				if tt.conversationID == "" && ok {
+					t.Errorf("payload should not include conversation_id, got %q", got)
+				}
+				if tt.conversationID != "" && got != tt.conversationID {
+					t.Errorf("payload conversation_id = %q, want %q", got, tt.conversationID)
+				}
+			})
+
+			s, err := newTestClient(server.URL).Ask(context.Background(), Query{Query: "q", ConversationID: tt.conversationID})
+			if err != nil {
+				t.Fatalf("Ask() error: %v", err)
+			}
+			defer s.Close()
+
+			if s.ConversationID() != "" {
+				t.Error("ConversationID() should be empty before the chunk is read")
+			}
+			collectEvents(t, s)
+			if s.ConversationID() != "conv-123" {
+				t.Errorf("ConversationID() = %q, want %q", s.ConversationID(), "conv-123")
+			}
+		})
+	}
+}
+
+func TestClientAskLanguage(t *testing.T) {
+	server := newNDJSONServer(t, []string{
+		`{"chunkType":"SOURCES","sources":[{"title":"Quickstart","url":"https://docs.github.com/en/actions/quickstart"},{"title":"CLI","url":"https://cli.github.com"}]}`,
+	}, func(r *http.Request, payload map[string]string) {
+		if payload["language"] != "ja" {
+			t.Errorf("payload language = %q, want %q", payload["language"], "ja")
+		}
+	})
+
+	s, err := newTestClient(server.URL).Ask(context.Background(), Query{Query: "q", Version: "free-pro-team@latest", Language: "ja"})
+	if err != nil {
+		t.Fatalf("Ask() error: %v", err)
+	}
+	defer s.Close()
+
+	events := collectEvents(t, s)
+	if len(events) != 1 || events[0].Sources[0].URL != "https://docs.github.com/ja/actions/quickstart" {
+		t.Errorf("events = %+v, want the docs link localized", events)
+	}
+	if got := s.Sources(); got[0].URL != "https://docs.github.com/ja/actions/quickstart" || got[1].URL != "https://cli.github.com" {
+		t.Errorf("Sources() = %+v", got)
+	}
+}
+
+func TestClientAskEvents(t *testing.T) {
+	server := newNDJSONServer(t, []string{
+		`{"chunkType":"CONVERSATION_ID","conversation_id":"conv-123"}`,
+		`{"chunkType":"MESSAGE_CHUNK","text":"GitHub is "}`,
+		``,
+		`{invalid json}`,
+		`{"chunkType":"MESSAGE_CHUNK","text":"a platform."}`,
+		`{"chunkType":"SOURCES","sources":[{"title":"GitHub Docs","url":"https://docs.github.com"},{"title":"CLI","url":"https://cli.github.com"}]}`,
+		`{"chunkType":"SOURCES","sources":[{"title":"GitHub Docs","url":"https://docs.github.com"},{"title":"API","url":"https://docs.github.com/api"}]}`,
+		`{"chunkType":"NO_CONTENT_SIGNAL"}`,
+		`{"chunkType":"INPUT_CONTENT_FILTER"}`,
+	}, nil)
+
+	s, err := newTestClient(server.URL).Ask(context.Background(), Query{Query: "q", Version: "free-pro-team@latest"})
+	if err != nil {
+		t.Fatalf("Ask() error: %v", err)
+	}
+	defer s.Close()
+
+	events := collectEvents(t, s)
+
+	wantTypes := []string{
+		ChunkConversationID,
+		ChunkMessage,
+		ChunkMessage,
+		ChunkSources,
+		ChunkSources,
+		ChunkNoContent,
+		ChunkInputFilter,
+	}
+	if len(events) != len(wantTypes) {
+		t.Fatalf("got %d events, want %d", len(events), len(wantTypes))
+	}
+	for i, want := range wantTypes {
+		if events[i].Type != want {
+			t.Errorf("events[%d].Type = %q, want %q", i, events[i].Type, want)
+		}
+	}
+
+	if events[0].ConversationID != "conv-123" {
+		t.Errorf("ConversationID = %q, want %q", events[0].ConversationID, "conv-123")
+	}
+	if text := events[1].Text + events[2].Text; text != "GitHub is a platform." {
+		t.Errorf("text = %q", text)
+	}
+	if len(events[3].Sources) != 2 {
+		t.Errorf("events[3].Sources has %d entries, want 2", len(events[3].Sources))
+	}
+
+	sources := s.Sources()
+	wantURLs := []string{"https://docs.github.com", "https://cli.github.com", "https://docs.github.com/api"}
+	if len(sources) != len(wantURLs) {
+		t.Fatalf("Sources() returned %d entries, want %d", len(sources), len(wantURLs))
+	}
+	for i, u := range wantURLs {
+		if sources[i].URL != u {
+			t.Errorf("Sources()[%d].URL = %q, want %q", i, sources[i].URL, u)
+		}
+	}
+}
+
+func TestClientAskNonOKStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	s, err := newTestClient(server.URL).Ask(context.Background(), Query{Query: "q"})
+	if err == nil {
+		s.Close()
+		t.Fatal("expected error for non-200 response")
+	}
+
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("error = %T, want *HTTPStatusError", err)
+	}
+	if statusErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("StatusCode = %d, want %d", statusErr.StatusCode, http.StatusInternalServerError)
+	}
+	if statusErr.Body != "boom" {
+		t.Errorf("Body = %q, want %q", statusErr.Body, "boom")
+	}
+	if !strings.Contains(err.Error(), "500") {
+		t.Errorf("error = %v, want status code in message", err)
+	}
+}
+
+func TestClientAskRequestFailed(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	_, err := newTestClient(url).Ask(context.Background(), Query{Query: "q"})
+	if !errors.Is(err, ErrRequestFailed) {
+		t.Errorf("error = %v, want ErrRequestFailed", err)
+	}
+}
+
+// failingReader returns data followed by a non-EOF error.
+type failingReader struct {
+	data string
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestStreamInterrupted(t *testing.T) {
+	body := io.NopCloser(&failingReader{data: `{"chunkType":"MESSAGE_CHUNK","text":"partial"}` + "\n"})
+	s := newStream(body, nil)
+
+	ev, err := s.Next()
+	if err != nil || ev.Text != "partial" {
+		t.Fatalf("Next() = %+v, %v; want partial message", ev, err)
+	}
+
+	_, err = s.Next()
+	if !errors.Is(err, ErrStreamInterrupted) {
+		t.Errorf("error = %v, want ErrStreamInterrupted", err)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("error = %v, should wrap the read error", err)
+	}
+}
+
+func TestClientDebugWriter(t *testing.T) {
+	server := newNDJSONServer(t, []string{
+		`{"chunkType":"MESSAGE_CHUNK","text":"hi"}`,
+		`{invalid json}`,
+	}, nil)
+
+	var debug bytes.Buffer
+	c := newTestClient(server.URL)
+	c.Debug = &debug
+
+	s, err := c.Ask(context.Background(), Query{Query: "q"})
+	if err != nil {
+		t.Fatalf("Ask() error: %v", err)
+	}
+	defer s.Close()
+	collectEvents(t, s)
+
+	want := "{\"chunkType\":\"MESSAGE_CHUNK\",\"text\":\"hi\"}\n{invalid json}\n"
+	if debug.String() != want {
+		t.Errorf("debug output = %q, want %q", debug.String(), want)
+	}
+}
+
+func TestStreamWithoutTrailingNewline(t *testing.T) {
+	body := io.NopCloser(strings.NewReader(`{"chunkType":"MESSAGE_CHUNK","text":"last"}`))
+	s := newStream(body, nil)
+
+	events := collectEvents(t, s)
+	if len(events) != 1 || events[0].Text != "last" {
+		t.Errorf("events = %+v, want single MESSAGE_CHUNK", events)
+	}
+}
+
+// flakyServer fails with the given statuses before answering normally.
+func flakyServer(t *testing.T, statuses []int, header http.Header) (*httptest.Server, *int) {
+	t.Helper()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= len(statuses) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			http.Error(w, "try again", statuses[calls-1])
+			return
+		}
+		_, _ = w.Write([]byte(`{"chunkType":"MESSAGE_CHUNK","text":"ok"}` + "\n"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// recordSleeps replaces the client's sleep with one that records delays.
+func recordSleeps(c *Client) *[]time.Duration {
+	var delays []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return &delays
+}
+
+func TestClientRetries(t *testing.T) {
+	server, calls := flakyServer(t, []int{http.StatusServiceUnavailable, http.StatusBadGateway}, nil)
+
+	c := newTestClient(server.URL)
+	c.Retries = 2
+	c.RetryBackoff = 100 * time.Millisecond
+	delays := recordSleeps(c)
+
+	var attempts []int
+	c.OnRetry = func(attempt int, delay time.Duration, err error) {
+		attempts = append(attempts, attempt)
+	}
+
+	s, err := c.Ask(context.Background(), Query{Query: "q"})
+	if err != nil {
+		t.Fatalf("Ask() error: %v", err)
+	}
+	defer s.Close()
+
+	if *calls != 3 {
+		t.Errorf("server called %d times, want 3", *calls)
+	}
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("OnRetry attempts = %v, want [1 2]", attempts)
+	}
+
+	// Exponential backoff with jitter: [base/2, base], then [base, 2*base]
+	if len(*delays) != 2 {
+		t.Fatalf("slept %d times, want 2", len(*delays))
+	}
+	if d := (*delays)[0]; d < 50*time.Millisecond || d > 100*time.Millisecond {
+		t.Errorf("first delay = %s, want between 50ms and 100ms", d)
+	}
+	if d := (*delays)[1]; d < 100*time.Millisecond || d > 200*time.Millisecond {
+		t.Errorf("second delay = %s, want between 100ms and 200ms", d)
+	}
+}
+
+func TestClientRetriesExhausted(t *testing.T) {
+	server, calls := flakyServer(t, []int{500, 500, 500}, nil)
+
+	c := newTestClient(server.URL)
+	c.Retries = 1
+	recordSleeps(c)
+
+	_, err := c.Ask(context.Background(), Query{Query: "q"})
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 500 {
+		t.Errorf("error = %v, want 500 HTTPStatusError", err)
+	}
+	if *calls != 2 {
+		t.Errorf("server called %d times, want 2", *calls)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	server, calls := flakyServer(t, []int{http.StatusBadRequest}, nil)
+
+	c := newTestClient(server.URL)
+	c.Retries = 3
+	recordSleeps(c)
+
+	if _, err := c.Ask(context.Background(), Query{Query: "q"}); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if *calls != 1 {
+		t.Errorf("server called %d times, want 1", *calls)
+	}
+}
+
+func TestClientHonorsRetryAfter(t *testing.T) {
+	server, _ := flakyServer(t, []int{http.StatusTooManyRequests}, http.Header{"Retry-After": {"3"}})
+
+	c := newTestClient(server.URL)
+	c.Retries = 1
+	delays := recordSleeps(c)
+
+	s, err := c.Ask(context.Background(), Query{Query: "q"})
+	if err != nil {
+		t.Fatalf("Ask() error: %v", err)
+	}
+	defer s.Close()
+
+	if len(*delays) != 1 || (*delays)[0] != 3*time.Second {
+		t.Errorf("delays = %v, want [3s]", *delays)
+	}
+}
+
+func TestClientRetryAfterTooLong(t *testing.T) {
+	server, calls := flakyServer(t, []int{http.StatusTooManyRequests}, http.Header{"Retry-After": {"3600"}})
+
+	c := newTestClient(server.URL)
+	c.Retries = 1
+	delays := recordSleeps(c)
+
+	if _, err := c.Ask(context.Background(), Query{Query: "q"}); err == nil {
+		t.Fatal("expected error when Retry-After exceeds the limit")
+	}
+	if *calls != 1 || len(*delays) != 0 {
+		t.Errorf("calls = %d, delays = %v; want no retry", *calls, *delays)
+	}
+}
+
+func TestRetryDelayCapped(t *testing.T) {
+	c := NewClient()
+	c.RetryBackoff = time.Second
+	for attempt := 0; attempt < 70; attempt++ {
+		d := c.retryDelay(attempt, ErrRequestFailed)
+		if d <= 0 || d > maxRetryBackoff {
+			t.Fatalf("retryDelay(%d) = %s, want (0, %s]", attempt, d, maxRetryBackoff)
+		}
+	}
+}
+
+// stallingServer writes the given lines and then hangs until the client
+// goes away. With stallHeaders it hangs before sending any response.
+func stallingServer(t *testing.T, stallHeaders bool, lines ...string) *httptest.Server {
+	t.Helper()
+	stop := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if !stallHeaders {
+			w.WriteHeader(http.StatusOK)
+			for _, l := range lines {
+				_, _ = w.Write([]byte(l + "\n"))
+			}
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-r.Context().Done():
+		case <-stop:
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(stop) })
+	return server
+}
+
+func TestClientIdleTimeoutBeforeResponse(t *testing.T) {
+	server := stallingServer(t, true)
+
+	c := newTestClient(server.URL)
+	c.IdleTimeout = 50 * time.Millisecond
+
+	_, err := c.Ask(context.Background(), Query{Query: "q"})
+	if !errors.Is(err, ErrRequestFailed) || !errors.Is(err, ErrIdleTimeout) {
+		t.Errorf("error = %v, want ErrRequestFailed wrapping ErrIdleTimeout", err)
+	}
+	if !IsRetryable(err) {
+		t.Error("an idle timeout before the response should be retryable")
+	}
+}
+
+func TestClientIdleTimeoutMidStream(t *testing.T) {
+	server := stallingServer(t, false, `{"chunkType":"MESSAGE_CHUNK","text":"partial"}`)
+
+	c := newTestClient(server.URL)
+	c.IdleTimeout = 50 * time.Millisecond
+
+	s, err := c.Ask(context.Background(), Query{Query: "q"})
+	if err != nil {
+		t.Fatalf("Ask() error: %v", err)
+	}
+	defer s.Close()
+
+	if ev, err := s.Next(); err != nil || ev.Text != "partial" {
+		t.Fatalf("Next() = %+v, %v; want partial message", ev, err)
+	}
+
+	_, err = s.Next()
+	if !errors.Is(err, ErrStreamInterrupted) || !errors.Is(err, ErrIdleTimeout) {
+		t.Errorf("error = %v, want ErrStreamInterrupted wrapping ErrIdleTimeout", err)
+	}
+}
+
+func TestClientCancelMidStream(t *testing.T) {
+	server := stallingServer(t, false, `{"chunkType":"MESSAGE_CHUNK","text":"partial"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := newTestClient(server.URL)
+	s, err := c.Ask(ctx, Query{Query: "q"})
+	if err != nil {
+		t.Fatalf("Ask() error: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Next(); err != nil {
+		t.Fatalf("Next() error: %v", err)
+	}
+
+	cancel()
+	_, err = s.Next()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if ExitCode(err) != ExitInterrupted {
+		t.Errorf("ExitCode() = %d, want %d", ExitCode(err), ExitInterrupted)
+	}
+}
@@ -2,11 +2,15 @@ package askdocs
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestExitCouldNotAnswerOutput(t *testing.T) {
@@ -99,55 +103,39 @@ func TestFixImagesEdgeCases(t *testing.T) {
 	}
 }
 
-func TestLoadSupportedVersionsFileNotFound(t *testing.T) {
-	// Save current working directory
-	// Save original working directory
+func TestLoadSupportedVersionsIgnoresWorkingDirectory(t *testing.T) {
 	origWd, _ := os.Getwd()
 	defer func() {
 		_ = os.Chdir(origWd)
 	}()
 
-	// Create temporary directory without data file
+	// A data directory in the working directory is no longer read.
 	tmpDir := t.TempDir()
 	_ = os.Chdir(tmpDir)
+	_ = os.Mkdir("data", 0755)
+	_ = os.WriteFile("data/supported-versions.json", []byte(`{"supportedVersions": ["1.0"]}`), 0644)
 
-	_, err := LoadSupportedVersions()
-	if err == nil {
-		t.Error("Expected error when data file not found")
+	versions, err := LoadSupportedVersions()
+	if err != nil {
+		t.Fatalf("LoadSupportedVersions() error: %v", err)
 	}
-
-	if !strings.Contains(err.Error(), "no such file or directory") {
-		t.Errorf("Expected file not found error, got: %v", err)
+	if IsVersionSupported("1.0") || len(versions.SupportedVersions) == 0 {
+		t.Errorf("LoadSupportedVersions() = %+v, want the built-in list", versions)
 	}
 }
 
-func TestLoadSupportedVersionsInvalidJSON(t *testing.T) {
-	// Save current working directory
-	origWd, _ := os.Getwd()
-	defer func() {
-		_ = os.Chdir(origWd)
-	}()
-
-	// Create temporary directory with invalid JSON file
-	tmpDir := t.TempDir()
-	_ = os.Chdir(tmpDir)
-
-	// Create data directory and invalid JSON file
-	_ = os.Mkdir("data", 0755)
-	invalidJSON := `{"lastUpdated": "invalid json`
-	err := os.WriteFile("data/supported-versions.json", []byte(invalidJSON), 0644)
-	if err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
-	}
-
-	_, err = LoadSupportedVersions()
+func TestParseSupportedVersionsInvalidJSON(t *testing.T) {
+	_, err := ParseSupportedVersions([]byte(`{"lastUpdated": "invalid json`))
 	if err == nil {
-		t.Error("Expected error when JSON is invalid")
+		t.Fatal("Expected error when JSON is invalid")
 	}
-
 	if !strings.Contains(err.Error(), "unexpected end of JSON input") && !strings.Contains(err.Error(), "invalid character") {
 		t.Errorf("Expected JSON parsing error, got: %v", err)
 	}
+
+	if _, err := ParseSupportedVersions([]byte(`{"supportedVersions": []}`)); err == nil {
+		t.Error("Expected error when no versions are listed")
+	}
 }
 
 func TestFixTablesMoreCases(t *testing.T) {
@@ -259,3 +247,143 @@ func TestRegexPatterns(t *testing.T) {
 		})
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, ExitOK},
+		{"generic", errors.New("boom"), ExitError},
+		{"no content", ErrNoContent, ExitNoContent},
+		{"content filtered", ErrContentFiltered, ExitContentFiltered},
+		{"wrapped no content", fmt.Errorf("asking: %w", ErrNoContent), ExitNoContent},
+		{"no offline match", ErrNoMatch, ExitNoContent},
+		{"not found", &HTTPStatusError{StatusCode: 404, Status: "404 Not Found"}, ExitHTTPClientError},
+		{"rate limited", &HTTPStatusError{StatusCode: 429, Status: "429 Too Many Requests"}, ExitHTTPServerError},
+		{"server error", &HTTPStatusError{StatusCode: 503, Status: "503 Service Unavailable"}, ExitHTTPServerError},
+		{"request failed", fmt.Errorf("%w: dial tcp: refused", ErrRequestFailed), ExitRequestFailed},
+		{"stream interrupted", fmt.Errorf("%w: unexpected EOF", ErrStreamInterrupted), ExitStreamInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{ErrNoContent, "⚠️  The AI could not answer your question."},
+		{ErrNoMatch, "⚠️  No article in the offline docs index matches your question for this version."},
+		{ErrContentFiltered, "⚠️  Your question was blocked by the content filter. Try rephrasing it."},
+		{
+			&HTTPStatusError{StatusCode: 502, Status: "502 Bad Gateway", Body: "upstream timed out"},
+			"error: unexpected response status: 502 Bad Gateway: upstream timed out",
+		},
+		{errors.New("boom"), "error: boom"},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorMessage(tt.err); got != tt.want {
+			t.Errorf("ErrorMessage(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBodyExcerpt(t *testing.T) {
+	if got := bodyExcerpt([]byte("  line one\n\tline two  ")); got != "line one line two" {
+		t.Errorf("bodyExcerpt() = %q", got)
+	}
+
+	long := bodyExcerpt([]byte(strings.Repeat("x", maxBodyExcerpt*2)))
+	if !strings.HasSuffix(long, "…") || len(long) != maxBodyExcerpt+len("…") {
+		t.Errorf("bodyExcerpt() did not truncate, got %d bytes", len(long))
+	}
+}
+
+func TestExitOutput(t *testing.T) {
+	// Test the output and exit code of Exit by running it in a subprocess
+	if os.Getenv("TEST_EXIT") == "1" {
+		Exit(&HTTPStatusError{StatusCode: 503, Status: "503 Service Unavailable", Body: "try later"})
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestExitOutput")
+	cmd.Env = append(os.Environ(), "TEST_EXIT=1")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	if exitError, ok := err.(*exec.ExitError); ok {
+		if exitError.ExitCode() != ExitHTTPServerError {
+			t.Errorf("Expected exit code %d, got %d", ExitHTTPServerError, exitError.ExitCode())
+		}
+	} else {
+		t.Error("Expected exit error, but command succeeded")
+	}
+
+	if strings.Contains(stdout.String(), "503") {
+		t.Errorf("Expected nothing on stdout, got: %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "503 Service Unavailable: try later") {
+		t.Errorf("Expected status and body excerpt in stderr, got: %q", stderr.String())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.in); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got <= 0 || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %s, want within a minute", future, got)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"no content", ErrNoContent, false},
+		{"bad request", &HTTPStatusError{StatusCode: 400}, false},
+		{"rate limited", &HTTPStatusError{StatusCode: 429}, true},
+		{"server error", &HTTPStatusError{StatusCode: 502}, true},
+		{"connection refused", fmt.Errorf("%w: refused", ErrRequestFailed), true},
+		{"canceled", fmt.Errorf("%w: %w", ErrRequestFailed, context.Canceled), false},
+		{"stream interrupted", ErrStreamInterrupted, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package askdocs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Failure modes returned by Client.Ask and Stream.Next.
+var (
+	// ErrNoContent means the AI could not find an answer (NO_CONTENT_SIGNAL).
+	ErrNoContent = errors.New("the AI could not answer your question")
+
+	// ErrContentFiltered means the question was rejected (INPUT_CONTENT_FILTER).
+	ErrContentFiltered = errors.New("the question was blocked by the content filter")
+
+	// ErrRequestFailed means the request never got a response, e.g. DNS or
+	// connection errors.
+	ErrRequestFailed = errors.New("request failed")
+
+	// ErrStreamInterrupted means the response stopped before it was complete.
+	ErrStreamInterrupted = errors.New("answer stream was interrupted")
+
+	// ErrIdleTimeout means no data arrived within Client.IdleTimeout. It is
+	// wrapped by ErrRequestFailed or ErrStreamInterrupted.
+	ErrIdleTimeout = errors.New("no data received within the idle timeout")
+
+	// ErrNoMatch means no article in the Offline index matches the question
+	// for its version. It wraps ErrNoContent.
+	ErrNoMatch error = noMatchError{}
+)
+
+// noMatchError is ErrNoMatch: its own message, but still an ErrNoContent.
+type noMatchError struct{}
+
+func (noMatchError) Error() string {
+	return "no article in the offline docs index matches the question"
+}
+
+func (noMatchError) Unwrap() error { return ErrNoContent }
+
+// HTTPStatusError is returned when the API responds with a non-200 status.
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string        // excerpt of the response body
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+func (e *HTTPStatusError) Error() string {
+	msg := "unexpected response status: " + e.Status
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// maxBodyExcerpt caps how much of an error response is kept.
+const maxBodyExcerpt = 512
+
+// bodyExcerpt collapses whitespace and truncates an error response body.
+func bodyExcerpt(body []byte) string {
+	s := strings.Join(strings.Fields(string(body)), " ")
+	if len(s) > maxBodyExcerpt {
+		s = s[:maxBodyExcerpt] + "…"
+	}
+	return s
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an
+// HTTP date. It returns 0 when the header is missing or invalid.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Exit codes, one per failure mode, so scripts can decide what to retry.
+const (
+	ExitOK                = 0
+	ExitError             = 1 // usage, configuration or unexpected errors
+	ExitNoContent         = 2
+	ExitContentFiltered   = 3
+	ExitHTTPClientError   = 4   // 4xx other than 429
+	ExitHTTPServerError   = 5   // 5xx and 429, retryable
+	ExitRequestFailed     = 6   // retryable
+	ExitStreamInterrupted = 7   // retryable
+	ExitInterrupted       = 130 // cancelled with Ctrl-C
+)
+
+// ExitCode maps an error to the process exit code for its failure mode.
+func ExitCode(err error) int {
+	var statusErr *HTTPStatusError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.Is(err, ErrNoContent):
+		return ExitNoContent
+	case errors.Is(err, ErrContentFiltered):
+		return ExitContentFiltered
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500 {
+			return ExitHTTPServerError
+		}
+		return ExitHTTPClientError
+	case errors.Is(err, ErrRequestFailed):
+		return ExitRequestFailed
+	case errors.Is(err, ErrStreamInterrupted):
+		return ExitStreamInterrupted
+	}
+	return ExitError
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// connection errors, 429 and 5xx responses, and interrupted streams.
+func IsRetryable(err error) bool {
+	switch ExitCode(err) {
+	case ExitHTTPServerError, ExitRequestFailed, ExitStreamInterrupted:
+		return true
+	}
+	return false
+}
+
+// ErrorMessage returns the message shown to the user for err.
+func ErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, ErrNoMatch):
+		return "⚠️  No article in the offline docs index matches your question for this version."
+	case errors.Is(err, ErrNoContent):
+		return "⚠️  The AI could not answer your question."
+	case errors.Is(err, ErrContentFiltered):
+		return "⚠️  Your question was blocked by the content filter. Try rephrasing it."
+	case errors.Is(err, context.Canceled):
+		return "interrupted"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "error: timed out waiting for an answer"
+	}
+	return fmt.Sprintf("error: %v", err)
+}
@@ -0,0 +1,636 @@
+package askdocs
+
+import (
+	"cmp"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// indexFormat changes whenever Index does; older saved indexes must be
+// rebuilt.
+const indexFormat = 1
+
+// Ranking weights: title and intro terms count as if repeated this often.
+const (
+	titleWeight = 3
+	introWeight = 2
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// maxExcerptBytes caps the excerpt quoted from each article.
+const maxExcerptBytes = 600
+
+// Index is a search index of the articles in a github/docs checkout.
+type Index struct {
+	Format    int
+	DocsDir   string // the checkout the index was built from
+	BuiltAt   time.Time
+	Articles  []Article
+	DocFreq   map[string]int // number of articles each term appears in
+	AvgLength float64
+}
+
+// Article is one docs page in an Index.
+type Article struct {
+	Path  string // URL path below the language and version, e.g. "actions/get-started/quickstart"
+	Title string
+	Intro string
+
+	// Versions is the article's versions front matter by short plan name
+	// (fpt, ghec, ghes), with features resolved: "*" or a release range such
+	// as ">=3.15", alternatives separated by "||".
+	Versions map[string]string
+
+	Blocks []string       // Markdown paragraphs, headings and code blocks
+	Terms  map[string]int // term counts, title and intro weighted up
+	Length int            // total of Terms
+}
+
+// Hit is an article matching a search.
+type Hit struct {
+	Article *Article
+	Score   float64
+	URL     string // the article's docs.github.com URL for the searched version
+	Excerpt string // the block that best matches the search
+}
+
+// frontMatter is the part of an article's front matter the index reads.
+type frontMatter struct {
+	Title    string         `yaml:"title"`
+	Intro    string         `yaml:"intro"`
+	Versions map[string]any `yaml:"versions"`
+}
+
+// BuildIndex indexes the Markdown articles under content/ in a clone of
+// github/docs; dir may also be the content directory itself. Liquid data
+// variables and reusables are filled in from data/ and every other Liquid
+// tag is dropped, so text that only applies to some versions is indexed for
+// all of them.
+func BuildIndex(dir string) (*Index, error) {
+	contentDir := filepath.Join(dir, "content")
+	if fi, err := os.Stat(contentDir); err != nil || !fi.IsDir() {
+		if filepath.Base(filepath.Clean(dir)) != "content" {
+			return nil, fmt.Errorf("%s has no content directory; use a clone of github/docs", dir)
+		}
+		contentDir = dir
+	}
+	dataDir := filepath.Join(filepath.Dir(contentDir), "data")
+	lq := newLiquid(dataDir)
+	features := loadFeatures(filepath.Join(dataDir, "features"))
+
+	ix := &Index{Format: indexFormat, DocsDir: dir, BuiltAt: time.Now(), DocFreq: map[string]int{}}
+	err := filepath.WalkDir(contentDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" || d.Name() == "README.md" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(contentDir, path)
+		if err != nil {
+			return err
+		}
+		if a, ok := parseArticle(data, articlePath(rel), lq, features); ok {
+			ix.Articles = append(ix.Articles, a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ix.Articles) == 0 {
+		return nil, fmt.Errorf("no articles found in %s", contentDir)
+	}
+
+	titles := make(map[string]string, len(ix.Articles))
+	total := 0
+	for _, a := range ix.Articles {
+		titles[a.Path] = a.Title
+		total += a.Length
+		for t := range a.Terms {
+			ix.DocFreq[t]++
+		}
+	}
+	for i := range ix.Articles {
+		for j, b := range ix.Articles[i].Blocks {
+			ix.Articles[i].Blocks[j] = absoluteLinks(b, titles)
+		}
+	}
+	ix.AvgLength = float64(total) / float64(len(ix.Articles))
+	return ix, nil
+}
+
+// articlePath turns a file path below content/ into its URL path:
+// actions/index.md is "actions" and quickstart.md is "quickstart".
+func articlePath(rel string) string {
+	p := strings.TrimSuffix(filepath.ToSlash(rel), ".md")
+	if p == "index" {
+		return ""
+	}
+	return strings.TrimSuffix(p, "/index")
+}
+
+// parseArticle reads an article's front matter and body. Files without
+// versions are not published and are skipped.
+func parseArticle(data []byte, path string, lq *liquid, features map[string]map[string]string) (Article, bool) {
+	head, body, ok := splitFrontMatter(string(data))
+	if !ok {
+		return Article{}, false
+	}
+	var fm frontMatter
+	if yaml.Unmarshal([]byte(head), &fm) != nil || len(fm.Versions) == 0 {
+		return Article{}, false
+	}
+
+	a := Article{
+		Path:     path,
+		Title:    lq.render(fm.Title),
+		Intro:    lq.render(fm.Intro),
+		Versions: resolveVersions(fm.Versions, features),
+		Terms:    map[string]int{},
+	}
+	a.Blocks = splitBlocks(lq.render(body))
+
+	add := func(s string, weight int) {
+		for _, t := range terms(s) {
+			a.Terms[t] += weight
+			a.Length += weight
+		}
+	}
+	add(a.Title, titleWeight)
+	add(a.Intro, introWeight)
+	for _, b := range a.Blocks {
+		add(b, 1)
+	}
+	return a, true
+}
+
+// splitFrontMatter splits a Markdown file into its YAML front matter and body.
+func splitFrontMatter(s string) (head, body string, ok bool) {
+	s = strings.TrimPrefix(strings.ReplaceAll(s, "\r\n", "\n"), "\ufeff")
+	rest, ok := strings.CutPrefix(s, "---\n")
+	if !ok {
+		return "", "", false
+	}
+	head, body, ok = strings.Cut(rest, "\n---\n")
+	if !ok {
+		head, ok = strings.CutSuffix(rest, "\n---")
+	}
+	return head, body, ok
+}
+
+// resolveVersions flattens versions front matter into ranges by plan,
+// replacing `feature: name` with the versions in data/features/name.yml.
+func resolveVersions(versions map[string]any, features map[string]map[string]string) map[string]string {
+	out := map[string]string{}
+	add := func(plan, r string) {
+		if r = strings.TrimSpace(r); r == "" {
+			return
+		}
+		if prev, ok := out[plan]; ok && prev != r {
+			r = prev + " || " + r
+		}
+		out[plan] = r
+	}
+	for plan, v := range versions {
+		if plan != "feature" {
+			add(plan, fmt.Sprint(v))
+			continue
+		}
+		var names []string
+		switch v := v.(type) {
+		case string:
+			names = []string{v}
+		case []any:
+			for _, n := range v {
+				names = append(names, fmt.Sprint(n))
+			}
+		}
+		for _, name := range names {
+			for plan, r := range features[name] {
+				add(plan, r)
+			}
+		}
+	}
+	return out
+}
+
+// loadFeatures reads the versions of every feature in data/features.
+func loadFeatures(dir string) map[string]map[string]string {
+	features := map[string]map[string]string{}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".yml")
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var f struct {
+			Versions map[string]any `yaml:"versions"`
+		}
+		if yaml.Unmarshal(data, &f) == nil {
+			features[name] = resolveVersions(f.Versions, nil)
+		}
+	}
+	return features
+}
+
+// Available reports whether the article is published for v. Enterprise
+// server releases are matched against the article's range; "latest" is the
+// newest supported release.
+func (a *Article) Available(v Version) bool {
+	r, ok := a.Versions[shortPlan(v.Plan)]
+	if !ok {
+		return false
+	}
+	if v.Plan != PlanEnterpriseServer {
+		return true
+	}
+	release := v.Release
+	if release == "latest" {
+		_, release = supportedReleases()
+	}
+	return matchRange(r, release)
+}
+
+// URL returns the article's docs.github.com URL for v, in English.
+func (a *Article) URL(v Version) string {
+	u := "https://docs.github.com/en"
+	switch v.Plan {
+	case PlanEnterpriseCloud:
+		u += "/" + PlanEnterpriseCloud + "@latest"
+	case PlanEnterpriseServer:
+		release := v.Release
+		if release == "latest" {
+			_, release = supportedReleases()
+		}
+		u += "/" + PlanEnterpriseServer + "@" + release
+	}
+	if a.Path != "" {
+		u += "/" + a.Path
+	}
+	return u
+}
+
+// shortPlan returns the name github/docs front matter uses for a plan.
+func shortPlan(plan string) string {
+	switch plan {
+	case PlanEnterpriseCloud:
+		return "ghec"
+	case PlanEnterpriseServer:
+		return "ghes"
+	}
+	return "fpt"
+}
+
+// rangeRe matches one comparison in a release range, e.g. ">=3.15".
+var rangeRe = regexp.MustCompile(`(>=|<=|>|<|=)?\s*(\d+(?:\.\d+)?)`)
+
+// matchRange reports whether release satisfies a semver-style range such as
+// "*", ">=3.14", ">3.9 <3.13" or ">=3.16 || =3.12".
+func matchRange(r, release string) bool {
+	for _, alt := range strings.Split(r, "||") {
+		alt = strings.TrimSpace(alt)
+		if alt == "*" || alt == "" {
+			return true
+		}
+		matches := rangeRe.FindAllStringSubmatch(alt, -1)
+		ok := len(matches) > 0
+		for _, m := range matches {
+			c := CompareReleases(release, m[2])
+			switch m[1] {
+			case ">=":
+				ok = ok && c >= 0
+			case "<=":
+				ok = ok && c <= 0
+			case ">":
+				ok = ok && c > 0
+			case "<":
+				ok = ok && c < 0
+			default:
+				ok = ok && c == 0
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Search returns the n articles published for v that best match query, best
+// first, ranked by BM25.
+func (ix *Index) Search(query string, v Version, n int) []Hit {
+	qterms := slices.Compact(slices.Sorted(slices.Values(terms(query))))
+	if len(qterms) == 0 {
+		return nil
+	}
+
+	var hits []Hit
+	count := float64(len(ix.Articles))
+	for i := range ix.Articles {
+		a := &ix.Articles[i]
+		score := 0.0
+		for _, t := range qterms {
+			tf := float64(a.Terms[t])
+			if tf == 0 {
+				continue
+			}
+			df := float64(ix.DocFreq[t])
+			idf := math.Log(1 + (count-df+0.5)/(df+0.5))
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(a.Length)/ix.AvgLength)
+			score += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+		if score > 0 && a.Available(v) {
+			hits = append(hits, Hit{Article: a, Score: score})
+		}
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Article.Path, b.Article.Path))
+	})
+	hits = hits[:min(n, len(hits))]
+	for i := range hits {
+		hits[i].URL = hits[i].Article.URL(v)
+		hits[i].Excerpt = hits[i].Article.excerpt(qterms)
+	}
+	return hits
+}
+
+// excerpt returns the block with the most distinct query terms together
+// with its section heading, in bold so it stays below the article title,
+// cut to maxExcerptBytes.
+func (a *Article) excerpt(qterms []string) string {
+	best, bestScore := -1, 0
+	for i, b := range a.Blocks {
+		seen := map[string]bool{}
+		for _, t := range terms(b) {
+			if slices.Contains(qterms, t) {
+				seen[t] = true
+			}
+		}
+		if len(seen) > bestScore {
+			best, bestScore = i, len(seen)
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	text := a.Blocks[best]
+	switch {
+	case isHeading(text) && best+1 < len(a.Blocks):
+		text = boldHeading(text) + "\n\n" + a.Blocks[best+1]
+	case best > 0 && isHeading(a.Blocks[best-1]):
+		text = boldHeading(a.Blocks[best-1]) + "\n\n" + text
+	}
+	if len(text) > maxExcerptBytes {
+		cut := strings.LastIndexAny(text[:maxExcerptBytes], "\n ")
+		if cut <= 0 {
+			cut = maxExcerptBytes
+		}
+		text = strings.TrimRight(text[:cut], " \n") + " …"
+		if strings.Count(text, "```")%2 == 1 {
+			text += "\n```"
+		}
+	}
+	return text
+}
+
+// isHeading reports whether a block is a Markdown heading.
+func isHeading(block string) bool {
+	return strings.HasPrefix(block, "#") && !strings.Contains(block, "\n")
+}
+
+// boldHeading turns a heading into bold text.
+func boldHeading(block string) string {
+	return "**" + strings.TrimSpace(strings.TrimLeft(block, "#")) + "**"
+}
+
+// splitBlocks splits Markdown at blank lines, keeping each fenced code block
+// whole and dropping HTML comments.
+func splitBlocks(body string) []string {
+	body = htmlCommentRe.ReplaceAllString(body, "")
+	var blocks []string
+	var cur []string
+	fenced := false
+	flush := func() {
+		if b := strings.TrimSpace(strings.Join(cur, "\n")); b != "" {
+			blocks = append(blocks, b)
+		}
+		cur = nil
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		if !fenced && strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		cur = append(cur, line)
+	}
+	flush()
+	return blocks
+}
+
+var (
+	htmlCommentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+	// docsLinkRe matches Markdown links to other docs pages, which github/docs
+	// writes as site-relative paths, often with AUTOTITLE as the text.
+	docsLinkRe = regexp.MustCompile(`\[([^\]]*)\]\((/[^)\s]*)\)`)
+)
+
+// absoluteLinks points site-relative links at docs.github.com and replaces
+// AUTOTITLE with the linked article's title.
+func absoluteLinks(s string, titles map[string]string) string {
+	return docsLinkRe.ReplaceAllStringFunc(s, func(m string) string {
+		parts := docsLinkRe.FindStringSubmatch(m)
+		text, target := parts[1], parts[2]
+		if text == "AUTOTITLE" {
+			path, _, _ := strings.Cut(strings.Trim(target, "/"), "#")
+			text = cmp.Or(titles[strings.TrimPrefix(path, "en/")], path)
+		}
+		if !strings.HasPrefix(target, "/en/") && target != "/en" {
+			target = "/en" + target
+		}
+		return "[" + text + "](https://docs.github.com" + target + ")"
+	})
+}
+
+// stopWords are left out of the index.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "can": true, "do": true, "does": true, "for": true, "from": true,
+	"how": true, "i": true, "if": true, "in": true, "is": true, "it": true, "my": true,
+	"of": true, "on": true, "or": true, "the": true, "this": true, "to": true,
+	"what": true, "when": true, "which": true, "why": true, "with": true, "you": true,
+	"your": true,
+}
+
+// terms splits text into lowercase words, without stop words and with plural
+// endings removed.
+func terms(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := words[:0]
+	for _, w := range words {
+		if len(w) < 2 || stopWords[w] {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(w, "ies") && len(w) > 4:
+			w = w[:len(w)-3] + "y"
+		case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && len(w) > 3:
+			w = w[:len(w)-1]
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// liquid fills in the Liquid tags used in github/docs content.
+type liquid struct {
+	dataDir   string
+	variables map[string]string // e.g. "variables.product.prodname_actions"
+	reusables map[string]string
+}
+
+func newLiquid(dataDir string) *liquid {
+	lq := &liquid{dataDir: dataDir, variables: map[string]string{}, reusables: map[string]string{}}
+	entries, _ := os.ReadDir(filepath.Join(dataDir, "variables"))
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".yml")
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dataDir, "variables", e.Name()))
+		if err != nil {
+			continue
+		}
+		var vars map[string]any
+		if yaml.Unmarshal(data, &vars) == nil {
+			flatten(lq.variables, "variables."+name, vars)
+		}
+	}
+	return lq
+}
+
+// flatten adds the string values in m to out under prefix.key.
+func flatten(out map[string]string, prefix string, m map[string]any) {
+	for k, v := range m {
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(out, prefix+"."+k, v)
+		case string:
+			out[prefix+"."+k] = v
+		}
+	}
+}
+
+// liquidTagRe matches a Liquid tag or output, e.g. {% data x %} or {{ x }}.
+var liquidTagRe = regexp.MustCompile(`(?s){%-?\s*(.*?)\s*-?%}|{{-?.*?-?}}`)
+
+// render replaces {% data ... %} with the variable or reusable it names and
+// removes every other tag.
+func (lq *liquid) render(s string) string {
+	return lq.renderDepth(s, 0)
+}
+
+func (lq *liquid) renderDepth(s string, depth int) string {
+	return liquidTagRe.ReplaceAllStringFunc(s, func(tag string) string {
+		m := liquidTagRe.FindStringSubmatch(tag)
+		name, ok := strings.CutPrefix(m[1], "data ")
+		if !ok || depth > 3 {
+			return ""
+		}
+		name = strings.TrimSpace(name)
+		if v, ok := lq.variables[name]; ok {
+			return lq.renderDepth(v, depth+1)
+		}
+		if strings.HasPrefix(name, "reusables.") {
+			return lq.renderDepth(lq.reusable(name), depth+1)
+		}
+		return ""
+	})
+}
+
+// reusable returns the Markdown of a reusable such as
+// reusables.actions.workflow-basic-example, or "".
+func (lq *liquid) reusable(name string) string {
+	if text, ok := lq.reusables[name]; ok {
+		return text
+	}
+	path := filepath.Join(lq.dataDir, filepath.FromSlash(strings.ReplaceAll(name, ".", "/"))+".md")
+	data, _ := os.ReadFile(path)
+	lq.reusables[name] = strings.TrimSpace(string(data))
+	return lq.reusables[name]
+}
+
+// Save writes the index to path, replacing it atomically.
+func (ix *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(f).Encode(ix)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// ErrIndexFormat is returned by LoadIndex for an index saved by another
+// version of the extension.
+var ErrIndexFormat = errors.New("the offline index was built by another version of gh-ask-docs")
+
+// LoadIndex reads an index written by Save.
+func LoadIndex(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ix Index
+	if err := gob.NewDecoder(f).Decode(&ix); err != nil {
+		return nil, fmt.Errorf("reading the offline index: %w", err)
+	}
+	if ix.Format != indexFormat {
+		return nil, ErrIndexFormat
+	}
+	return &ix, nil
+}
@@ -0,0 +1,238 @@
+package askdocs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDocs writes a small github/docs checkout and returns its root.
+func writeDocs(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"data/variables/product.yml":             "prodname_actions: GitHub Actions\nprodname_ghe_server: GitHub Enterprise Server\n",
+		"data/reusables/actions/runner-intro.md": "Runners are the machines that run {% data variables.product.prodname_actions %} jobs.\n",
+		"data/features/saml-sso.yml":             "versions:\n  ghec: '*'\n  ghes: '>=3.18'\n",
+		"content/README.md":                      "# Content\n",
+		"content/index.md":                       "---\ntitle: GitHub Docs\nversions:\n  fpt: '*'\n  ghec: '*'\n  ghes: '*'\n---\n",
+		"content/actions/index.md":               "---\ntitle: '{% data variables.product.prodname_actions %}'\nintro: Automate your workflow.\nversions:\n  fpt: '*'\n  ghec: '*'\n  ghes: '*'\n---\n",
+		"content/actions/runners.md": "---\ntitle: About self-hosted runners\nintro: 'Host your own runners for {% data variables.product.prodname_actions %}.'\nversions:\n  fpt: '*'\n  ghec: '*'\n  ghes: '*'\n---\n\n" +
+			"{% data reusables.actions.runner-intro %}\n\n" +
+			"## Adding a self-hosted runner\n\n" +
+			"{% ifversion ghes %}Ask your site administrator first.{% endif %} Add self-hosted runners in the repository settings. See [AUTOTITLE](/actions).\n\n" +
+			"```yaml\nruns-on: self-hosted\n```\n",
+		"content/admin/saml.md": "---\ntitle: Configuring SAML single sign-on\nintro: Sign in with your identity provider.\nversions:\n  feature: saml-sso\n---\n\n" +
+			"<!-- internal note about saml -->\nConfigure SAML single sign-on for your enterprise.\n",
+		"content/admin/old-saml.md":     "---\ntitle: Configuring SAML on older releases\nversions:\n  ghes: '<3.18'\n---\n\nThe old way to configure SAML single sign-on.\n",
+		"content/drafts/unpublished.md": "---\ntitle: Unpublished SAML runners\n---\n\nSAML runners.\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBuildIndex(t *testing.T) {
+	ix, err := BuildIndex(writeDocs(t))
+	if err != nil {
+		t.Fatalf("BuildIndex() error: %v", err)
+	}
+	paths := map[string]*Article{}
+	for i, a := range ix.Articles {
+		paths[a.Path] = &ix.Articles[i]
+	}
+	for _, p := range []string{"", "actions", "actions/runners", "admin/saml", "admin/old-saml"} {
+		if paths[p] == nil {
+			t.Errorf("article %q not indexed", p)
+		}
+	}
+	if len(ix.Articles) != 5 {
+		t.Errorf("indexed %d articles, want 5 (README.md and articles without versions are skipped)", len(ix.Articles))
+	}
+
+	if a := paths["actions"]; a != nil && a.Title != "GitHub Actions" {
+		t.Errorf("title = %q, want the variable filled in", a.Title)
+	}
+	if a := paths["admin/saml"]; a != nil && a.Versions["ghes"] != ">=3.18" {
+		t.Errorf("versions = %v, want the saml-sso feature's", a.Versions)
+	}
+	if a := paths["admin/saml"]; a != nil && strings.Contains(strings.Join(a.Blocks, "\n"), "internal note") {
+		t.Errorf("blocks = %q, HTML comments should be dropped", a.Blocks)
+	}
+
+	runners := strings.Join(paths["actions/runners"].Blocks, "\n\n")
+	for _, want := range []string{
+		"Runners are the machines that run GitHub Actions jobs.",
+		"Ask your site administrator first. Add self-hosted runners",
+		"See [GitHub Actions](https://docs.github.com/en/actions).",
+		"```yaml\nruns-on: self-hosted\n```",
+	} {
+		if !strings.Contains(runners, want) {
+			t.Errorf("runners article = %q, want it to contain %q", runners, want)
+		}
+	}
+}
+
+func TestBuildIndexErrors(t *testing.T) {
+	if _, err := BuildIndex(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no content directory") {
+		t.Errorf("BuildIndex() of an empty directory error = %v", err)
+	}
+
+	content := filepath.Join(t.TempDir(), "content")
+	if err := os.Mkdir(content, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuildIndex(content); err == nil || !strings.Contains(err.Error(), "no articles") {
+		t.Errorf("BuildIndex() of an empty content directory error = %v", err)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix, err := BuildIndex(writeDocs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query   string
+		version string
+		want    []string // URLs, best first
+	}{
+		{"how do I add self-hosted runners to GitHub Actions?", "free-pro-team@latest", []string{
+			"https://docs.github.com/en/actions/runners",
+			"https://docs.github.com/en/actions",
+			"https://docs.github.com/en",
+		}},
+		{"configure SAML", "free-pro-team@latest", nil},
+		{"configure SAML", "enterprise-cloud@latest", []string{"https://docs.github.com/en/enterprise-cloud@latest/admin/saml"}},
+		{"configure SAML", "enterprise-server@3.18", []string{"https://docs.github.com/en/enterprise-server@3.18/admin/saml"}},
+		{"configure SAML", "enterprise-server@3.17", []string{"https://docs.github.com/en/enterprise-server@3.17/admin/old-saml"}},
+		{"the", "free-pro-team@latest", nil},
+	}
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, h := range ix.Search(tt.query, v, 3) {
+			got = append(got, h.URL)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("Search(%q, %s) = %q, want %q", tt.query, tt.version, got, tt.want)
+		}
+	}
+
+	hits := ix.Search("add self-hosted runner", Version{PlanFreeProTeam, "latest"}, 1)
+	if len(hits) != 1 || !strings.HasPrefix(hits[0].Excerpt, "**Adding a self-hosted runner**\n\nAsk your site administrator") {
+		t.Errorf("hits = %+v, want the matching section as the excerpt", hits)
+	}
+}
+
+func TestMatchRange(t *testing.T) {
+	tests := []struct {
+		r, release string
+		want       bool
+	}{
+		{"*", "3.16", true},
+		{">=3.18", "3.18", true},
+		{">=3.18", "3.17", false},
+		{">3.9", "3.16", true},
+		{"<3.18", "3.18", false},
+		{"> 3.16 < 3.19", "3.18", true},
+		{"> 3.16 < 3.19", "3.19", false},
+		{"=3.17", "3.17", true},
+		{"<3.17 || >=3.20", "3.21", true},
+		{"<3.17 || >=3.20", "3.18", false},
+		{"later", "3.18", false},
+	}
+	for _, tt := range tests {
+		if got := matchRange(tt.r, tt.release); got != tt.want {
+			t.Errorf("matchRange(%q, %q) = %v, want %v", tt.r, tt.release, got, tt.want)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := strings.Join(terms("How do I configure the Repositories' branches, with GitHub Actions?"), " ")
+	if want := "configure repository branche github action"; got != want {
+		t.Errorf("terms() = %q, want %q", got, want)
+	}
+}
+
+func TestIndexSaveLoad(t *testing.T) {
+	ix, err := BuildIndex(writeDocs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cache", "index.gob")
+	if err := ix.Save(path); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	loaded, err := LoadIndex(path)
+	if err != nil {
+		t.Fatalf("LoadIndex() error: %v", err)
+	}
+	if len(loaded.Articles) != len(ix.Articles) || loaded.DocsDir != ix.DocsDir || loaded.AvgLength != ix.AvgLength {
+		t.Errorf("loaded index differs: %d articles from %q", len(loaded.Articles), loaded.DocsDir)
+	}
+
+	ix.Format = indexFormat + 1
+	if err := ix.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadIndex(path); !errors.Is(err, ErrIndexFormat) {
+		t.Errorf("LoadIndex() of another format error = %v, want ErrIndexFormat", err)
+	}
+}
+
+func TestOfflineAsk(t *testing.T) {
+	ix, err := BuildIndex(writeDocs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &Offline{Index: ix, Results: 1}
+
+	s, err := o.Ask(context.Background(), Query{Query: "self-hosted runners", Version: "free-pro-team@latest", Language: "ja"})
+	if err != nil {
+		t.Fatalf("Ask() error: %v", err)
+	}
+	defer s.Close()
+	var types []string
+	var text string
+	for {
+		ev, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		types = append(types, ev.Type)
+		text += ev.Text
+	}
+	if strings.Join(types, " ") != ChunkSources+" "+ChunkMessage {
+		t.Errorf("event types = %q", types)
+	}
+	if src := s.Sources(); len(src) != 1 || src[0].URL != "https://docs.github.com/ja/actions/runners" {
+		t.Errorf("sources = %+v, want the localized article link", src)
+	}
+	if !strings.HasPrefix(text, "### [About self-hosted runners](https://docs.github.com/ja/actions/runners)\n\nHost your own runners for GitHub Actions.\n\n") {
+		t.Errorf("answer = %q", text)
+	}
+
+	if _, err := o.Ask(context.Background(), Query{Query: "kubernetes", Version: "free-pro-team@latest"}); !errors.Is(err, ErrNoMatch) || !errors.Is(err, ErrNoContent) {
+		t.Errorf("Ask() without a match error = %v, want ErrNoMatch", err)
+	}
+}
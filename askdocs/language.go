@@ -0,0 +1,108 @@
+package askdocs
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultLanguage is the docs language used when none is given.
+const DefaultLanguage = "en"
+
+// Language is a language docs.github.com is published in.
+type Language struct {
+	Code string // e.g. "ja", the first segment of a docs path
+	Name string
+}
+
+// Languages lists every language docs.github.com is published in.
+var Languages = []Language{
+	{"en", "English"},
+	{"es", "Español"},
+	{"ja", "日本語"},
+	{"pt", "Português do Brasil"},
+	{"zh", "简体中文"},
+	{"ru", "Русский"},
+	{"fr", "Français"},
+	{"ko", "한국어"},
+	{"de", "Deutsch"},
+}
+
+// docsHost is the host whose links LocalizeURL rewrites.
+const docsHost = "docs.github.com"
+
+// ParseLanguage returns the docs language code for s. Locale forms such as
+// "ja_JP.UTF-8" or "pt-BR" are accepted; the region and encoding are ignored.
+// An empty string is DefaultLanguage.
+func ParseLanguage(s string) (string, error) {
+	code := languageCode(s)
+	if code == "" {
+		return DefaultLanguage, nil
+	}
+	if !isLanguage(code) {
+		codes := make([]string, len(Languages))
+		for i, l := range Languages {
+			codes[i] = l.Code
+		}
+		return "", fmt.Errorf("unsupported docs language %q (use %s)", s, strings.Join(codes, ", "))
+	}
+	return code, nil
+}
+
+// LanguageFromEnv returns the docs language of the user's locale, from
+// LC_ALL, LC_MESSAGES or LANG in that order. It returns DefaultLanguage when
+// the locale is unset, C/POSIX, or not a language the docs are published in.
+func LanguageFromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		if code := languageCode(v); isLanguage(code) {
+			return code
+		}
+		return DefaultLanguage
+	}
+	return DefaultLanguage
+}
+
+// LocalizeURL rewrites a docs.github.com link to the same page in lang, e.g.
+// https://docs.github.com/en/actions to https://docs.github.com/ja/actions.
+// Links that are not to a localized docs page are returned unchanged.
+func LocalizeURL(rawURL, lang string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host != docsHost || !isLanguage(lang) {
+		return rawURL
+	}
+	first, rest, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if !isLanguage(first) || first == lang {
+		return rawURL
+	}
+	u.Path = "/" + lang
+	if rest != "" {
+		u.Path += "/" + rest
+	}
+	u.RawPath = ""
+	return u.String()
+}
+
+// languageCode reduces a language or locale name to its lowercase language
+// code: "ja_JP.UTF-8" is "ja".
+func languageCode(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s, _, _ = strings.Cut(s, ".")
+	s, _, _ = strings.Cut(s, "@")
+	s, _, _ = strings.Cut(s, "_")
+	s, _, _ = strings.Cut(s, "-")
+	return s
+}
+
+func isLanguage(code string) bool {
+	for _, l := range Languages {
+		if l.Code == code {
+			return true
+		}
+	}
+	return false
+}
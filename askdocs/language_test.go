@@ -0,0 +1,67 @@
+package askdocs
+
+import "testing"
+
+func TestParseLanguage(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "en", false},
+		{"ja", "ja", false},
+		{"JA", "ja", false},
+		{"pt-BR", "pt", false},
+		{"zh_CN.UTF-8", "zh", false},
+		{"de_DE@euro", "de", false},
+		{"tlh", "", true},
+		{"C", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLanguage(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLanguage(%q) = %q, %v; want %q (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLanguageFromEnv(t *testing.T) {
+	tests := []struct {
+		lcAll, lcMessages, lang string
+		want                    string
+	}{
+		{"", "", "", "en"},
+		{"", "", "ja_JP.UTF-8", "ja"},
+		{"", "ko_KR.UTF-8", "ja_JP.UTF-8", "ko"},
+		{"fr_FR.UTF-8", "ko_KR.UTF-8", "ja_JP.UTF-8", "fr"},
+		{"C", "", "ja_JP.UTF-8", "en"},
+		{"", "", "nl_NL.UTF-8", "en"},
+	}
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_MESSAGES", tt.lcMessages)
+		t.Setenv("LANG", tt.lang)
+		if got := LanguageFromEnv(); got != tt.want {
+			t.Errorf("LanguageFromEnv() with LC_ALL=%q LC_MESSAGES=%q LANG=%q = %q, want %q", tt.lcAll, tt.lcMessages, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestLocalizeURL(t *testing.T) {
+	tests := []struct {
+		in, lang, want string
+	}{
+		{"https://docs.github.com/en/actions/quickstart", "ja", "https://docs.github.com/ja/actions/quickstart"},
+		{"https://docs.github.com/en/enterprise-server@3.17/admin#saml", "de", "https://docs.github.com/de/enterprise-server@3.17/admin#saml"},
+		{"https://docs.github.com/es", "pt", "https://docs.github.com/pt"},
+		{"https://docs.github.com/en/actions", "en", "https://docs.github.com/en/actions"},
+		{"https://docs.github.com/actions", "ja", "https://docs.github.com/actions"},
+		{"https://github.com/en/actions", "ja", "https://github.com/en/actions"},
+		{"https://docs.github.com/en/actions", "tlh", "https://docs.github.com/en/actions"},
+	}
+	for _, tt := range tests {
+		if got := LocalizeURL(tt.in, tt.lang); got != tt.want {
+			t.Errorf("LocalizeURL(%q, %q) = %q, want %q", tt.in, tt.lang, got, tt.want)
+		}
+	}
+}
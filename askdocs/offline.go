@@ -0,0 +1,94 @@
+package askdocs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// Asker answers queries: the AI Search Client, or an Offline index.
+type Asker interface {
+	Ask(ctx context.Context, q Query) (*Stream, error)
+}
+
+// DefaultOfflineResults is how many articles an offline answer quotes.
+const DefaultOfflineResults = 3
+
+// Offline answers queries from an Index, for when docs.github.com cannot be
+// reached. The answer quotes the best-matching articles for the query's
+// version, with links; nothing is generated, and conversations are not kept.
+type Offline struct {
+	Index *Index
+
+	// Results is how many articles are quoted; DefaultOfflineResults if 0.
+	Results int
+
+	// Debug, when non-nil, receives every NDJSON line of the answer.
+	Debug io.Writer
+}
+
+// Ask searches the index and returns the answer as a Stream of the same
+// events the AI Search API sends: the sources, then one message chunk per
+// article. It returns ErrNoMatch when no article matches.
+func (o *Offline) Ask(ctx context.Context, q Query) (*Stream, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	v, err := ParseVersion(q.Version)
+	if err != nil {
+		return nil, err
+	}
+	language := q.Language
+	if language == "" {
+		language = DefaultLanguage
+	}
+
+	n := o.Results
+	if n <= 0 {
+		n = DefaultOfflineResults
+	}
+	hits := o.Index.Search(q.Query, v, n)
+
+	if len(hits) == 0 {
+		return nil, ErrNoMatch
+	}
+	sources := make([]Source, len(hits))
+	for i := range hits {
+		hits[i].URL = LocalizeURL(hits[i].URL, language)
+		sources[i] = Source{Title: hits[i].Article.Title, URL: hits[i].URL}
+	}
+	raw, err := json.Marshal(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(GenericLine{ChunkType: ChunkSources, Sources: raw})
+	for i, h := range hits {
+		_ = enc.Encode(GenericLine{ChunkType: ChunkMessage, Text: h.markdown(i == len(hits)-1)})
+	}
+
+	s := newStream(io.NopCloser(&body), o.Debug)
+	s.language = language
+	return s, nil
+}
+
+// markdown quotes the hit as a linked heading, the intro and the excerpt.
+func (h Hit) markdown(last bool) string {
+	var b strings.Builder
+	b.WriteString("### " + AutoLink(h.URL, h.Article.Title) + "\n\n")
+	if h.Article.Intro != "" {
+		b.WriteString(h.Article.Intro + "\n\n")
+	}
+	if h.Excerpt != "" && h.Excerpt != h.Article.Intro {
+		b.WriteString(h.Excerpt + "\n\n")
+	}
+	if last {
+		return strings.TrimRight(b.String(), "\n") + "\n"
+	}
+	return b.String()
+}
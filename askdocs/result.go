@@ -0,0 +1,43 @@
+package askdocs
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Result is the complete answer to a single Query.
+type Result struct {
+	Query          string   `json:"query"`
+	Version        string   `json:"version"`
+	Language       string   `json:"language,omitempty"`
+	Answer         string   `json:"answer"`
+	Sources        []Source `json:"sources"`
+	ConversationID string   `json:"conversation_id,omitempty"`
+	Error          string   `json:"error,omitempty"`
+	Partial        bool     `json:"partial,omitempty"` // the stream was interrupted
+	Cached         bool     `json:"cached,omitempty"`  // served from the local answer cache
+	Timings        Timings  `json:"timings"`
+}
+
+// Timings records when an answer was requested and how long it took.
+type Timings struct {
+	StartedAt    time.Time `json:"started_at"`
+	FirstChunkMS int64     `json:"first_chunk_ms"`
+	TotalMS      int64     `json:"total_ms"`
+}
+
+// Line converts the event back into its NDJSON representation. Sources are
+// re-encoded from the parsed list so the output is normalized.
+func (e Event) Line() GenericLine {
+	line := GenericLine{
+		ChunkType:      e.Type,
+		Text:           e.Text,
+		ConversationID: e.ConversationID,
+	}
+	if len(e.Sources) > 0 {
+		if data, err := json.Marshal(e.Sources); err == nil {
+			line.Sources = data
+		}
+	}
+	return line
+}
@@ -0,0 +1,85 @@
+package askdocs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			"message chunk",
+			Event{Type: ChunkMessage, Text: "Hello"},
+			`{"chunkType":"MESSAGE_CHUNK","text":"Hello"}`,
+		},
+		{
+			"conversation ID",
+			Event{Type: ChunkConversationID, ConversationID: "conv-123"},
+			`{"chunkType":"CONVERSATION_ID","conversation_id":"conv-123"}`,
+		},
+		{
+			"sources",
+			Event{Type: ChunkSources, Sources: []Source{{Title: "Docs", URL: "https://docs.github.com"}}},
+			`{"chunkType":"SOURCES","sources":[{"title":"Docs","url":"https://docs.github.com"}]}`,
+		},
+		{
+			"no content",
+			Event{Type: ChunkNoContent},
+			`{"chunkType":"NO_CONTENT_SIGNAL"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.event.Line())
+			if err != nil {
+				t.Fatalf("Marshal() error: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Line() = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultJSON(t *testing.T) {
+	res := Result{
+		Query:          "What is GitHub?",
+		Version:        "free-pro-team@latest",
+		Answer:         "A platform.",
+		Sources:        []Source{{Title: "Docs", URL: "https://docs.github.com"}},
+		ConversationID: "conv-123",
+		Timings: Timings{
+			StartedAt:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			FirstChunkMS: 120,
+			TotalMS:      900,
+		},
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+
+	for _, want := range []string{
+		`"query":"What is GitHub?"`,
+		`"version":"free-pro-team@latest"`,
+		`"answer":"A platform."`,
+		`"sources":[{"title":"Docs","url":"https://docs.github.com"}]`,
+		`"conversation_id":"conv-123"`,
+		`"timings":{"started_at":"2025-01-02T03:04:05Z","first_chunk_ms":120,"total_ms":900}`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON missing %s: %s", want, data)
+		}
+	}
+	if strings.Contains(string(data), `"error"`) {
+		t.Errorf("error should be omitted when empty: %s", data)
+	}
+}
@@ -0,0 +1,200 @@
+package askdocs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/data"
+)
+
+// SupportedVersions lists the GitHub Enterprise Server releases the docs
+// cover, in the format of data/supported-versions.json.
+type SupportedVersions struct {
+	LastUpdated       string   `json:"lastUpdated"`
+	SupportedVersions []string `json:"supportedVersions"`
+	LatestVersion     string   `json:"latestVersion"`
+
+	// Releases holds the dates of each supported release, when known.
+	Releases map[string]ReleaseDates `json:"releases,omitempty"`
+
+	// Refreshed is set when the list came from RefreshedVersionsPath rather
+	// than the copy built into the binary.
+	Refreshed bool `json:"-"`
+}
+
+// ReleaseDates are the release and deprecation dates of a GHES release, as
+// YYYY-MM-DD.
+type ReleaseDates struct {
+	ReleaseDate     string `json:"releaseDate"`
+	DeprecationDate string `json:"deprecationDate"`
+}
+
+// DeprecationWarning is how long before its deprecation date a release is
+// flagged as deprecating soon.
+const DeprecationWarning = 90 * 24 * time.Hour
+
+// VersionInfo describes one docs version: a plan, or a GHES release.
+type VersionInfo struct {
+	Version         string `json:"version"` // e.g. enterprise-server@3.17
+	Plan            string `json:"plan"`
+	Name            string `json:"name"`
+	Latest          bool   `json:"latest,omitempty"`
+	ReleaseDate     string `json:"releaseDate,omitempty"`
+	DeprecationDate string `json:"deprecationDate,omitempty"`
+	DeprecatingSoon bool   `json:"deprecatingSoon,omitempty"`
+	Deprecated      bool   `json:"deprecated,omitempty"` // the deprecation date has passed
+}
+
+// List returns every docs version: free-pro-team and enterprise-cloud, then
+// the supported GHES releases from oldest to newest. Releases deprecated
+// within DeprecationWarning of now are flagged as deprecating soon, and
+// releases whose deprecation date has passed, which a list that has not
+// been refreshed can still hold, as deprecated.
+func (v *SupportedVersions) List(now time.Time) []VersionInfo {
+	list := []VersionInfo{
+		{Version: PlanFreeProTeam + "@latest", Plan: PlanFreeProTeam, Name: "GitHub Free, Pro & Team", Latest: true},
+		{Version: PlanEnterpriseCloud + "@latest", Plan: PlanEnterpriseCloud, Name: "GitHub Enterprise Cloud", Latest: true},
+	}
+
+	releases := slices.Clone(v.SupportedVersions)
+	slices.SortFunc(releases, CompareReleases)
+	for _, release := range releases {
+		info := VersionInfo{
+			Version: PlanEnterpriseServer + "@" + release,
+			Plan:    PlanEnterpriseServer,
+			Name:    "GitHub Enterprise Server " + release,
+			Latest:  release == v.LatestVersion,
+		}
+		if d, ok := v.Releases[release]; ok {
+			info.ReleaseDate = d.ReleaseDate
+			info.DeprecationDate = d.DeprecationDate
+			if deprecated, err := time.Parse(time.DateOnly, d.DeprecationDate); err == nil {
+				info.Deprecated = !now.Before(deprecated)
+				info.DeprecatingSoon = !info.Deprecated && deprecated.Sub(now) <= DeprecationWarning
+			}
+		}
+		list = append(list, info)
+	}
+	return list
+}
+
+// RefreshedVersionsPath is where `gh ask-docs versions refresh` saves the
+// supported versions it fetches. LoadSupportedVersions prefers that file
+// when it is newer than the built-in list.
+var RefreshedVersionsPath string
+
+// LoadSupportedVersions returns the supported enterprise server versions:
+// the built-in data/supported-versions.json, or the refreshed copy at
+// RefreshedVersionsPath when that is newer.
+func LoadSupportedVersions() (*SupportedVersions, error) {
+	baseline, err := ParseSupportedVersions(data.SupportedVersions)
+	if err != nil {
+		return nil, fmt.Errorf("built-in supported versions: %w", err)
+	}
+	if RefreshedVersionsPath == "" {
+		return baseline, nil
+	}
+
+	raw, err := os.ReadFile(RefreshedVersionsPath)
+	if err != nil {
+		return baseline, nil
+	}
+	refreshed, err := ParseSupportedVersions(raw)
+	if err != nil || !refreshed.newerThan(baseline) {
+		return baseline, nil
+	}
+	refreshed.Refreshed = true
+	return refreshed, nil
+}
+
+// ParseSupportedVersions parses supported-versions.json. LatestVersion
+// defaults to the newest supported release.
+func ParseSupportedVersions(raw []byte) (*SupportedVersions, error) {
+	var versions SupportedVersions
+	if err := json.Unmarshal(raw, &versions); err != nil {
+		return nil, err
+	}
+	if len(versions.SupportedVersions) == 0 {
+		return nil, errors.New("no supported versions listed")
+	}
+	if versions.LatestVersion == "" {
+		versions.LatestVersion = slices.MaxFunc(versions.SupportedVersions, CompareReleases)
+	}
+	return &versions, nil
+}
+
+// newerThan reports whether v was updated after other.
+func (v *SupportedVersions) newerThan(other *SupportedVersions) bool {
+	a, errA := time.Parse(time.RFC3339, v.LastUpdated)
+	b, errB := time.Parse(time.RFC3339, other.LastUpdated)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return a.After(b)
+}
+
+// SupportedVersionsFromDates builds the supported versions from github/docs'
+// enterprise-dates.json: every release out by now and not yet deprecated.
+func SupportedVersionsFromDates(raw []byte, now time.Time) (*SupportedVersions, error) {
+	var dates map[string]struct {
+		ReleaseDate     string `json:"releaseDate"`
+		DeprecationDate string `json:"deprecationDate"`
+	}
+	if err := json.Unmarshal(raw, &dates); err != nil {
+		return nil, fmt.Errorf("parsing enterprise dates: %w", err)
+	}
+
+	versions := &SupportedVersions{
+		LastUpdated: now.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Releases:    map[string]ReleaseDates{},
+	}
+	for release, d := range dates {
+		released, err1 := time.Parse(time.DateOnly, d.ReleaseDate)
+		deprecated, err2 := time.Parse(time.DateOnly, d.DeprecationDate)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if !released.After(now) && deprecated.After(now) {
+			versions.SupportedVersions = append(versions.SupportedVersions, release)
+			versions.Releases[release] = ReleaseDates{ReleaseDate: d.ReleaseDate, DeprecationDate: d.DeprecationDate}
+		}
+	}
+	if len(versions.SupportedVersions) == 0 {
+		return nil, errors.New("enterprise dates list no supported releases")
+	}
+	slices.SortFunc(versions.SupportedVersions, CompareReleases)
+	versions.LatestVersion = versions.SupportedVersions[len(versions.SupportedVersions)-1]
+	return versions, nil
+}
+
+// CompareReleases orders major.minor releases such as "3.9" and "3.16"
+// numerically.
+func CompareReleases(a, b string) int {
+	as, bs := strings.SplitN(a, ".", 2), strings.SplitN(b, ".", 2)
+	for i := range 2 {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// IsVersionSupported reports whether the docs cover the given enterprise
+// server release, e.g. "3.17".
+func IsVersionSupported(version string) bool {
+	versions, err := LoadSupportedVersions()
+	return err == nil && slices.Contains(versions.SupportedVersions, version)
+}
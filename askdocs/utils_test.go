@@ -2,9 +2,14 @@ package askdocs
 
 import (
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/data"
 )
 
 func TestStripANSI(t *testing.T) {
@@ -73,11 +78,15 @@ func TestNormalizeVersionWithSupportedVersions(t *testing.T) {
 }
 
 func TestNormalizeVersionEdgeCases(t *testing.T) {
+	versions, err := LoadSupportedVersions()
+	if err != nil {
+		t.Fatalf("LoadSupportedVersions() error: %v", err)
+	}
 	tests := map[string]string{
 		"free-pro-team":            "free-pro-team@latest",
 		"enterprise-cloud":         "enterprise-cloud@latest",
 		"enterprise-server@latest": "enterprise-server@latest",
-		"enterprise-server@":       "enterprise-server@3.15", // empty version part falls back to default
+		"enterprise-server@":       "enterprise-server@" + versions.LatestVersion, // empty version part falls back to latest
 		"random-value":             "free-pro-team@latest",
 		"":                         "free-pro-team@latest",
 	}
@@ -90,22 +99,25 @@ func TestNormalizeVersionEdgeCases(t *testing.T) {
 	}
 }
 
-func TestNormalizeVersionWithLoadError(t *testing.T) {
+func TestNormalizeVersionOutsideRepo(t *testing.T) {
 	// Save original working directory
 	origWd, _ := os.Getwd()
 	defer func() {
 		_ = os.Chdir(origWd)
 	}()
 
-	// Change to a directory without data file to test fallback behavior
+	// The built-in list is used wherever the binary runs
 	tmpDir := t.TempDir()
 	_ = os.Chdir(tmpDir)
 
-	// Test with unsupported version when LoadSupportedVersions fails
+	versions, err := LoadSupportedVersions()
+	if err != nil {
+		t.Fatalf("LoadSupportedVersions() error: %v", err)
+	}
 	result := NormalizeVersion("enterprise-server@999.0")
-	expected := "enterprise-server@3.15" // ultimate fallback
+	expected := "enterprise-server@" + versions.LatestVersion
 	if result != expected {
-		t.Errorf("NormalizeVersion with load error should fallback to %q, got %q", expected, result)
+		t.Errorf("NormalizeVersion should fall back to %q, got %q", expected, result)
 	}
 }
 
@@ -126,36 +138,10 @@ func TestIsVersionSupported(t *testing.T) {
 	}
 }
 
-func TestIsVersionSupportedWithFallback(t *testing.T) {
-	// Save original working directory
-	origWd, _ := os.Getwd()
-	defer func() {
-		_ = os.Chdir(origWd)
-	}()
-
-	// Change to a directory without data file to test fallback
-	tmpDir := t.TempDir()
-	_ = os.Chdir(tmpDir)
-
-	// Test hardcoded fallback versions
-	hardcodedVersions := []string{"3.11", "3.12", "3.13", "3.14", "3.15", "3.16", "3.17"}
-	for _, version := range hardcodedVersions {
-		if !IsVersionSupported(version) {
-			t.Errorf("Hardcoded version %s should be supported in fallback", version)
-		}
-	}
-
-	// Test unsupported version with fallback
-	if IsVersionSupported("2.0") {
-		t.Errorf("Version 2.0 should not be supported even in fallback")
-	}
-}
-
 func TestLoadSupportedVersions(t *testing.T) {
 	versions, err := LoadSupportedVersions()
 	if err != nil {
-		t.Logf("Could not load supported versions (expected in test environment): %v", err)
-		return
+		t.Fatalf("LoadSupportedVersions() error: %v", err)
 	}
 
 	if len(versions.SupportedVersions) == 0 {
@@ -165,52 +151,155 @@ func TestLoadSupportedVersions(t *testing.T) {
 	if versions.LatestVersion == "" {
 		t.Errorf("Expected latest version to be set")
 	}
+
+	if versions.Refreshed {
+		t.Errorf("Expected the built-in list without RefreshedVersionsPath")
+	}
 }
 
-func TestLoadSupportedVersionsWithTestData(t *testing.T) {
-	// Create a temporary test data file
-	testData := `{
-		"lastUpdated": "2024-01-01T00:00:00.000Z",
+func TestLoadSupportedVersionsRefreshed(t *testing.T) {
+	orig := RefreshedVersionsPath
+	t.Cleanup(func() { RefreshedVersionsPath = orig })
+	RefreshedVersionsPath = filepath.Join(t.TempDir(), "supported-versions.json")
+
+	write := func(data string) {
+		if err := os.WriteFile(RefreshedVersionsPath, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	// Newer than the built-in list: used.
+	write(`{
+		"lastUpdated": "2999-01-01T00:00:00.000Z",
 		"supportedVersions": ["3.15", "3.16", "3.17"],
 		"latestVersion": "3.17"
+	}`)
+	versions, err := LoadSupportedVersions()
+	if err != nil {
+		t.Fatalf("Failed to load refreshed supported versions: %v", err)
+	}
+	if !versions.Refreshed || len(versions.SupportedVersions) != 3 || versions.LatestVersion != "3.17" {
+		t.Errorf("LoadSupportedVersions() = %+v, want the refreshed list", versions)
+	}
+	if !IsVersionSupported("3.15") {
+		t.Errorf("IsVersionSupported(3.15) should use the refreshed list")
+	}
+
+	// Older than the built-in list, or broken: ignored.
+	for _, data := range []string{
+		`{"lastUpdated": "2000-01-01T00:00:00.000Z", "supportedVersions": ["2.0"]}`,
+		`{"lastUpdated": "2999-01-01T00:00:00.000Z"`,
+	} {
+		write(data)
+		versions, err := LoadSupportedVersions()
+		if err != nil || versions.Refreshed {
+			t.Errorf("LoadSupportedVersions() = %+v, %v; want the built-in list for %s", versions, err, data)
+		}
+	}
+}
+
+func TestSupportedVersionsFromDates(t *testing.T) {
+	dates := `{
+		"3.9":  {"releaseDate": "2023-06-29", "deprecationDate": "2024-06-29"},
+		"3.10": {"releaseDate": "2023-08-24", "deprecationDate": "2024-09-24"},
+		"3.11": {"releaseDate": "2023-11-07", "deprecationDate": "2024-11-07"},
+		"3.12": {"releaseDate": "2024-03-06", "deprecationDate": "2025-03-06"}
 	}`
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
 
-	// Write to a temporary file
-	tmpDir := t.TempDir()
-	testFile := tmpDir + "/supported-versions.json"
-	err := os.WriteFile(testFile, []byte(testData), 0644)
+	versions, err := SupportedVersionsFromDates([]byte(dates), now)
 	if err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
+		t.Fatalf("SupportedVersionsFromDates() error: %v", err)
+	}
+	if want := []string{"3.10", "3.11", "3.12"}; !slices.Equal(versions.SupportedVersions, want) {
+		t.Errorf("SupportedVersions = %v, want %v", versions.SupportedVersions, want)
+	}
+	if versions.LatestVersion != "3.12" || versions.LastUpdated != "2024-07-01T00:00:00.000Z" {
+		t.Errorf("LatestVersion = %q, LastUpdated = %q", versions.LatestVersion, versions.LastUpdated)
+	}
+	if got := versions.Releases["3.10"]; got != (ReleaseDates{ReleaseDate: "2023-08-24", DeprecationDate: "2024-09-24"}) {
+		t.Errorf("Releases[3.10] = %+v", got)
+	}
+	if _, ok := versions.Releases["3.9"]; ok {
+		t.Errorf("Releases should only hold supported releases")
 	}
 
-	// Save and restore original working directory
-	origWd, _ := os.Getwd()
-	defer func() {
-		_ = os.Chdir(origWd)
-	}()
+	if _, err := SupportedVersionsFromDates([]byte(dates), now.AddDate(5, 0, 0)); err == nil {
+		t.Error("Expected error when every release is deprecated")
+	}
+}
 
-	// Change to temp directory so relative path works
-	_ = os.Chdir(tmpDir)
+func TestSupportedVersionsList(t *testing.T) {
+	versions := &SupportedVersions{
+		SupportedVersions: []string{"3.10", "3.9", "3.11"},
+		LatestVersion:     "3.11",
+		Releases: map[string]ReleaseDates{
+			"3.9":  {ReleaseDate: "2023-06-29", DeprecationDate: "2024-07-20"},
+			"3.10": {ReleaseDate: "2023-08-24", DeprecationDate: "2024-12-01"},
+		},
+	}
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
 
-	// Create data directory structure
-	_ = os.Mkdir("data", 0755)
-	_ = os.Rename("supported-versions.json", "data/supported-versions.json")
+	list := versions.List(now)
+	var got []string
+	for _, v := range list {
+		got = append(got, v.Version)
+	}
+	want := []string{"free-pro-team@latest", "enterprise-cloud@latest", "enterprise-server@3.9", "enterprise-server@3.10", "enterprise-server@3.11"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("List() versions = %v, want %v", got, want)
+	}
 
-	versions, err := LoadSupportedVersions()
-	if err != nil {
-		t.Fatalf("Failed to load test supported versions: %v", err)
+	if !list[2].DeprecatingSoon || list[2].DeprecationDate != "2024-07-20" {
+		t.Errorf("3.9 = %+v, want it flagged as deprecating soon", list[2])
+	}
+	if list[3].DeprecatingSoon || list[3].ReleaseDate != "2023-08-24" {
+		t.Errorf("3.10 = %+v, want dates without the flag", list[3])
+	}
+	if list[4].DeprecatingSoon || !list[4].Latest || list[4].ReleaseDate != "" {
+		t.Errorf("3.11 = %+v, want the latest release without dates", list[4])
+	}
+	if list[0].Plan != PlanFreeProTeam || list[1].Plan != PlanEnterpriseCloud {
+		t.Errorf("plans = %+v, %+v", list[0], list[1])
 	}
 
-	if len(versions.SupportedVersions) != 3 {
-		t.Errorf("Expected 3 supported versions, got %d", len(versions.SupportedVersions))
+	// After 3.9's deprecation date, it is deprecated rather than deprecating
+	// soon, while 3.10 is now within the warning.
+	list = versions.List(time.Date(2024, 9, 10, 0, 0, 0, 0, time.UTC))
+	if !list[2].Deprecated || list[2].DeprecatingSoon {
+		t.Errorf("3.9 = %+v, want it flagged as deprecated", list[2])
+	}
+	if list[3].Deprecated || !list[3].DeprecatingSoon {
+		t.Errorf("3.10 = %+v, want it flagged as deprecating soon", list[3])
 	}
+	if list[4].Deprecated || list[4].DeprecatingSoon {
+		t.Errorf("3.11 = %+v, want it unflagged without dates", list[4])
+	}
+}
 
-	if versions.LatestVersion != "3.17" {
-		t.Errorf("Expected latest version to be 3.17, got %s", versions.LatestVersion)
+func TestBuiltInReleaseDates(t *testing.T) {
+	versions, err := ParseSupportedVersions(data.SupportedVersions)
+	if err != nil {
+		t.Fatalf("ParseSupportedVersions() error: %v", err)
+	}
+	updated, err := time.Parse(time.RFC3339, versions.LastUpdated)
+	if err != nil {
+		t.Fatalf("lastUpdated %q: %v", versions.LastUpdated, err)
 	}
 
-	if versions.LastUpdated != "2024-01-01T00:00:00.000Z" {
-		t.Errorf("Expected lastUpdated to be 2024-01-01T00:00:00.000Z, got %s", versions.LastUpdated)
+	for _, v := range versions.List(updated) {
+		if v.Plan != PlanEnterpriseServer {
+			continue
+		}
+		released, err1 := time.Parse(time.DateOnly, v.ReleaseDate)
+		deprecated, err2 := time.Parse(time.DateOnly, v.DeprecationDate)
+		if err1 != nil || err2 != nil {
+			t.Errorf("%s has no release and deprecation dates: %+v", v.Version, v)
+			continue
+		}
+		if released.After(updated) || !deprecated.After(updated) {
+			t.Errorf("%s (released %s, deprecated %s) was not supported on %s", v.Version, v.ReleaseDate, v.DeprecationDate, versions.LastUpdated)
+		}
 	}
 }
 
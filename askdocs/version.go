@@ -0,0 +1,155 @@
+package askdocs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Docs plans, the part of a version before the @.
+const (
+	PlanFreeProTeam      = "free-pro-team"
+	PlanEnterpriseCloud  = "enterprise-cloud"
+	PlanEnterpriseServer = "enterprise-server"
+)
+
+var plans = []string{PlanFreeProTeam, PlanEnterpriseCloud, PlanEnterpriseServer}
+
+// planAliases are common short names for the plans, offered as suggestions.
+var planAliases = map[string]string{
+	"fpt":        PlanFreeProTeam,
+	"dotcom":     PlanFreeProTeam,
+	"github.com": PlanFreeProTeam,
+	"ghec":       PlanEnterpriseCloud,
+	"ghes":       PlanEnterpriseServer,
+	"ghe":        PlanEnterpriseServer,
+}
+
+// Version is a docs version such as enterprise-server@3.17.
+type Version struct {
+	Plan    string
+	Release string // "latest", or an enterprise-server release such as "3.17"
+}
+
+func (v Version) String() string {
+	return v.Plan + "@" + v.Release
+}
+
+// VersionError reports a version that could not be used as given, and the
+// version ParseVersion substituted for it.
+type VersionError struct {
+	Input      string
+	Reason     string
+	Suggestion string // the version the user probably meant, if any
+	Substitute Version
+}
+
+func (e *VersionError) Error() string {
+	msg := fmt.Sprintf("invalid docs version %q: %s", e.Input, e.Reason)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf("; did you mean %q?", e.Suggestion)
+	}
+	return msg
+}
+
+// ParseVersion parses a docs version: free-pro-team, enterprise-cloud or
+// enterprise-server, optionally followed by @latest or, for
+// enterprise-server, @<release>. An empty string is free-pro-team@latest.
+//
+// When s cannot be used as given, ParseVersion returns the version it falls
+// back to together with a *VersionError explaining the substitution.
+func ParseVersion(s string) (Version, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return Version{PlanFreeProTeam, "latest"}, nil
+	}
+
+	plan, release, hasRelease := strings.Cut(s, "@")
+	if !slices.Contains(plans, plan) {
+		err := &VersionError{
+			Input:      s,
+			Reason:     fmt.Sprintf("unknown plan %q (use free-pro-team, enterprise-cloud or enterprise-server@<release>)", plan),
+			Substitute: Version{PlanFreeProTeam, "latest"},
+		}
+		if p := closestPlan(plan); p != "" {
+			err.Suggestion = p
+			if hasRelease {
+				err.Suggestion += "@" + release
+			}
+		}
+		return err.Substitute, err
+	}
+
+	if !hasRelease || release == "latest" {
+		return Version{plan, "latest"}, nil
+	}
+	if plan != PlanEnterpriseServer {
+		err := &VersionError{
+			Input:      s,
+			Reason:     plan + " only has @latest",
+			Substitute: Version{plan, "latest"},
+		}
+		return err.Substitute, err
+	}
+
+	supported, latest := supportedReleases()
+	if slices.Contains(supported, release) {
+		return Version{plan, release}, nil
+	}
+	err := &VersionError{
+		Input:      s,
+		Reason:     fmt.Sprintf("GitHub Enterprise Server %s is not covered by the docs (supported: %s)", release, strings.Join(supported, ", ")),
+		Substitute: Version{plan, latest},
+	}
+	if release == "" {
+		err.Reason = "missing release after @"
+	}
+	return err.Substitute, err
+}
+
+// supportedReleases returns the enterprise-server releases the docs cover
+// and the latest of them.
+func supportedReleases() ([]string, string) {
+	versions, err := LoadSupportedVersions()
+	if err != nil {
+		return nil, "latest"
+	}
+	return versions.SupportedVersions, versions.LatestVersion
+}
+
+// closestPlan returns the plan a misspelled or abbreviated plan most likely
+// meant, or "".
+func closestPlan(s string) string {
+	if p, ok := planAliases[s]; ok {
+		return p
+	}
+	best, bestDist := "", 4 // suggest only within 3 edits
+	for _, p := range plans {
+		if d := editDistance(s, p); d < bestDist {
+			best, bestDist = p, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
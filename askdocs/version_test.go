@@ -0,0 +1,85 @@
+package askdocs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]Version{
+		"":                         {PlanFreeProTeam, "latest"},
+		"free-pro-team":            {PlanFreeProTeam, "latest"},
+		"free-pro-team@latest":     {PlanFreeProTeam, "latest"},
+		"enterprise-cloud":         {PlanEnterpriseCloud, "latest"},
+		"enterprise-cloud@latest":  {PlanEnterpriseCloud, "latest"},
+		"enterprise-server":        {PlanEnterpriseServer, "latest"},
+		"enterprise-server@latest": {PlanEnterpriseServer, "latest"},
+		"enterprise-server@3.17":   {PlanEnterpriseServer, "3.17"},
+		" Enterprise-Server@3.17 ": {PlanEnterpriseServer, "3.17"},
+	}
+	for in, want := range tests {
+		got, err := ParseVersion(in)
+		if err != nil || got != want {
+			t.Errorf("ParseVersion(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+}
+
+func TestParseVersionSubstitutes(t *testing.T) {
+	_, latest := supportedReleases()
+	tests := []struct {
+		in         string
+		want       Version
+		reason     string
+		suggestion string
+	}{
+		{"enterprise-server@3.9", Version{PlanEnterpriseServer, latest}, "not covered by the docs (supported: ", ""},
+		{"enterprise-server@", Version{PlanEnterpriseServer, latest}, "missing release", ""},
+		{"enterprise-cloud@3.17", Version{PlanEnterpriseCloud, "latest"}, "only has @latest", ""},
+		{"enterprise-sever@3.17", Version{PlanFreeProTeam, "latest"}, `unknown plan "enterprise-sever"`, "enterprise-server@3.17"},
+		{"free-pro-tem", Version{PlanFreeProTeam, "latest"}, "unknown plan", "free-pro-team"},
+		{"ghes@3.17", Version{PlanFreeProTeam, "latest"}, "unknown plan", "enterprise-server@3.17"},
+		{"ghec", Version{PlanFreeProTeam, "latest"}, "unknown plan", "enterprise-cloud"},
+		{"something-else", Version{PlanFreeProTeam, "latest"}, "unknown plan", ""},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.in)
+		var verr *VersionError
+		if !errors.As(err, &verr) {
+			t.Errorf("ParseVersion(%q) error = %v, want a *VersionError", tt.in, err)
+			continue
+		}
+		if got != tt.want || verr.Substitute != tt.want {
+			t.Errorf("ParseVersion(%q) = %v (substitute %v), want %v", tt.in, got, verr.Substitute, tt.want)
+		}
+		if !strings.Contains(verr.Reason, tt.reason) || verr.Suggestion != tt.suggestion {
+			t.Errorf("ParseVersion(%q) reason %q, suggestion %q; want %q, %q", tt.in, verr.Reason, verr.Suggestion, tt.reason, tt.suggestion)
+		}
+	}
+}
+
+func TestVersionErrorMessage(t *testing.T) {
+	_, err := ParseVersion("enterprise-sever@3.17")
+	want := `invalid docs version "enterprise-sever@3.17": unknown plan "enterprise-sever" (use free-pro-team, enterprise-cloud or enterprise-server@<release>); did you mean "enterprise-server@3.17"?`
+	if err == nil || err.Error() != want {
+		t.Errorf("error = %v\nwant %s", err, want)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"enterprise-sever", "enterprise-server", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
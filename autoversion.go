@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// versionAuto is the --version value that detects the docs version from the
+// gh host.
+const versionAuto = "auto"
+
+// detectVersion returns the docs version for host: free-pro-team for
+// github.com, enterprise-cloud for *.ghe.com, and otherwise the
+// enterprise-server release reported by the host's /meta endpoint.
+func detectVersion(ctx context.Context, host string) (string, error) {
+	host = strings.ToLower(host)
+	switch {
+	case host == defaultGitHubHost:
+		return "free-pro-team@latest", nil
+	case strings.HasSuffix(host, ".ghe.com"):
+		return "enterprise-cloud@latest", nil
+	}
+
+	// /meta is public on most instances; send gh's token when there is one
+	// for instances in private mode.
+	token, _ := githubToken(host)
+	client := &githubClient{
+		BaseURL:    githubAPIURL(host),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	var meta struct {
+		InstalledVersion string `json:"installed_version"`
+	}
+	if err := client.do(ctx, "GET", "/meta", nil, &meta); err != nil {
+		return "", fmt.Errorf("detecting the GitHub Enterprise Server version of %s (use --version to set it): %w", host, err)
+	}
+	if meta.InstalledVersion == "" {
+		return "", fmt.Errorf("%s did not report an installed_version; use --version to set it", host)
+	}
+	return serverDocsVersion(meta.InstalledVersion)
+}
+
+// serverDocsVersion maps a GHES installed_version such as "3.16.4" to the
+// docs version for its release. Releases the docs no longer (or don't yet)
+// cover get the nearest supported one, with a warning.
+func serverDocsVersion(installed string) (string, error) {
+	release, ok := parseRelease(installed)
+	if !ok {
+		return "", fmt.Errorf("unrecognized GitHub Enterprise Server version %q", installed)
+	}
+
+	supported, err := askdocs.LoadSupportedVersions()
+	if err != nil || len(supported.SupportedVersions) == 0 || slices.Contains(supported.SupportedVersions, release) {
+		return "enterprise-server@" + release, nil
+	}
+
+	versions := slices.Clone(supported.SupportedVersions)
+	slices.SortFunc(versions, askdocs.CompareReleases)
+	nearest := versions[len(versions)-1]
+	reason := "is newer than the docs"
+	if askdocs.CompareReleases(release, versions[0]) < 0 {
+		nearest = versions[0]
+		reason = "is no longer covered by the docs"
+	}
+	fmt.Fprintf(os.Stderr, "⚠️  GitHub Enterprise Server %s %s; using enterprise-server@%s\n", release, reason, nearest)
+	return "enterprise-server@" + nearest, nil
+}
+
+// parseRelease returns the major.minor part of a version like "3.16.4".
+func parseRelease(v string) (string, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(v), "v"), ".", 3)
+	if len(parts) < 2 {
+		return "", false
+	}
+	for _, p := range parts[:2] {
+		if _, err := strconv.Atoi(p); err != nil {
+			return "", false
+		}
+	}
+	return parts[0] + "." + parts[1], true
+}
+
+// resolveAutoVersion replaces "auto" in --version, --compare and any other
+// given versions with the version detected for the current gh host.
+func resolveAutoVersion(opts *options, more ...*string) error {
+	targets := append([]*string{&opts.version}, more...)
+	if slices.Contains(opts.compare, versionAuto) {
+		opts.compare = slices.Clone(opts.compare)
+		for i := range opts.compare {
+			targets = append(targets, &opts.compare[i])
+		}
+	}
+	if !slices.ContainsFunc(targets, func(v *string) bool { return *v == versionAuto }) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	host := githubHost()
+	version, err := detectVersion(ctx, host)
+	if err != nil {
+		return err
+	}
+	if opts.debug {
+		fmt.Fprintf(os.Stderr, "detected %s for %s\n", version, host)
+	}
+
+	for _, v := range targets {
+		if *v == versionAuto {
+			*v = version
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newFakeMeta makes ghes.example.com the gh host and serves its /meta.
+func newFakeMeta(t *testing.T, status int, body string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/meta" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer ghes-token" {
+			t.Errorf("Authorization = %q, want the enterprise token", got)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("GH_ASK_DOCS_GITHUB_API_URL", server.URL)
+	t.Setenv("GH_HOST", "ghes.example.com")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "ghes-token")
+}
+
+func TestDetectVersion(t *testing.T) {
+	for host, want := range map[string]string{
+		"github.com":        "free-pro-team@latest",
+		"GitHub.com":        "free-pro-team@latest",
+		"octo-corp.ghe.com": "enterprise-cloud@latest",
+		"OCTO-CORP.GHE.COM": "enterprise-cloud@latest",
+	} {
+		got, err := detectVersion(context.Background(), host)
+		if err != nil || got != want {
+			t.Errorf("detectVersion(%q) = %q, %v; want %q", host, got, err, want)
+		}
+	}
+}
+
+func TestDetectVersionFromMeta(t *testing.T) {
+	tests := []struct {
+		installed string
+		want      string
+	}{
+		{"3.17.2", "enterprise-server@3.17"},
+		{"3.21.0", "enterprise-server@3.21"},
+		{"3.9.1", "enterprise-server@3.16"},  // too old: oldest supported
+		{"3.30.0", "enterprise-server@3.21"}, // too new: latest supported
+	}
+	for _, tt := range tests {
+		t.Run(tt.installed, func(t *testing.T) {
+			newFakeMeta(t, http.StatusOK, `{"installed_version":"`+tt.installed+`","verifiable_password_authentication":true}`)
+			var got string
+			var err error
+			captureStderr(t, func() { got, err = detectVersion(context.Background(), "ghes.example.com") })
+			if err != nil || got != tt.want {
+				t.Errorf("detectVersion() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectVersionErrors(t *testing.T) {
+	newFakeMeta(t, http.StatusOK, `{"verifiable_password_authentication":true}`)
+	if _, err := detectVersion(context.Background(), "ghes.example.com"); err == nil {
+		t.Error("detectVersion() without installed_version should fail")
+	}
+
+	newFakeMeta(t, http.StatusUnauthorized, `{"message":"Must authenticate to access this API."}`)
+	if _, err := detectVersion(context.Background(), "ghes.example.com"); err == nil {
+		t.Error("detectVersion() should fail when /meta does")
+	}
+}
+
+func TestResolveAutoVersion(t *testing.T) {
+	newFakeMeta(t, http.StatusOK, `{"installed_version":"3.18.5"}`)
+
+	opts := defaultOptions()
+	opts.version = versionAuto
+	opts.compare = []string{"free-pro-team", versionAuto}
+	batchVersion := versionAuto
+	if err := resolveAutoVersion(&opts, &batchVersion); err != nil {
+		t.Fatalf("resolveAutoVersion() error: %v", err)
+	}
+	if opts.version != "enterprise-server@3.18" || opts.compare[1] != "enterprise-server@3.18" || batchVersion != "enterprise-server@3.18" {
+		t.Errorf("resolved %q, %v, %q; want enterprise-server@3.18 everywhere", opts.version, opts.compare, batchVersion)
+	}
+
+	// Nothing to detect: no request is made.
+	t.Setenv("GH_ASK_DOCS_GITHUB_API_URL", "http://127.0.0.1:0")
+	opts = defaultOptions()
+	opts.version = "enterprise-cloud"
+	if err := resolveAutoVersion(&opts); err != nil || opts.version != "enterprise-cloud" {
+		t.Errorf("resolveAutoVersion() = %q, %v; want the version untouched", opts.version, err)
+	}
+}
+
+func TestParseRelease(t *testing.T) {
+	for in, want := range map[string]string{"3.16.4": "3.16", "3.17": "3.17", "v3.18.0.rc1": "3.18"} {
+		if got, ok := parseRelease(in); !ok || got != want {
+			t.Errorf("parseRelease(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "3", "three.16", "3.x"} {
+		if _, ok := parseRelease(in); ok {
+			t.Errorf("parseRelease(%q) should fail", in)
+		}
+	}
+}
+
+func TestGitHubHostFromHostsFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GH_CONFIG_DIR", dir)
+	t.Setenv("GH_HOST", "")
+
+	if got := githubHost(); got != "github.com" {
+		t.Errorf("githubHost() without hosts.yml = %q", got)
+	}
+
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, "hosts.yml"), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("ghes.example.com:\n    user: octo\n    git_protocol: https\nother.example.com:\n    user: octo\n")
+	if got := githubHost(); got != "ghes.example.com" {
+		t.Errorf("githubHost() = %q, want the first gh host", got)
+	}
+
+	write("ghes.example.com:\n    user: octo\ngithub.com:\n    user: octo\n")
+	if got := githubHost(); got != "github.com" {
+		t.Errorf("githubHost() = %q, want github.com when gh is logged in there", got)
+	}
+
+	t.Setenv("GH_HOST", "override.example.com")
+	if got := githubHost(); got != "override.example.com" {
+		t.Errorf("githubHost() = %q, GH_HOST should win", got)
+	}
+}
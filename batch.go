@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// Report formats accepted by `batch --report`.
+const (
+	reportJSONL    = "jsonl"
+	reportMarkdown = "markdown"
+)
+
+// batchOptions configures `gh ask-docs batch`.
+type batchOptions struct {
+	input       string
+	output      string
+	report      string
+	concurrency int
+	rate        float64 // requests started per second, 0 = unlimited
+}
+
+// batchQuestion is one line of the input file.
+type batchQuestion struct {
+	Query   string `json:"query"`
+	Version string `json:"version,omitempty"`
+}
+
+// readBatchQuestions reads one question per line from a .txt file (blank
+// lines and # comments are skipped) or one {"query", "version"} object per
+// line from a .jsonl file.
+func readBatchQuestions(r io.Reader, jsonl bool) ([]batchQuestion, error) {
+	var questions []batchQuestion
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || (!jsonl && strings.HasPrefix(line, "#")) {
+			continue
+		}
+		if !jsonl {
+			questions = append(questions, batchQuestion{Query: line})
+			continue
+		}
+
+		var q batchQuestion
+		if err := json.Unmarshal([]byte(line), &q); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if strings.TrimSpace(q.Query) == "" {
+			return nil, fmt.Errorf("line %d: missing query", n)
+		}
+		questions = append(questions, q)
+	}
+	return questions, scanner.Err()
+}
+
+// reportFormatFor picks the report format: explicit, else Markdown for .md
+// outputs, else JSONL.
+func reportFormatFor(report, output string) string {
+	if report != "" {
+		return report
+	}
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".md", ".markdown":
+		return reportMarkdown
+	}
+	return reportJSONL
+}
+
+// runBatch answers every question in bo.input and writes one result per
+// question, in input order, to bo.output. Questions that fail are reported
+// and the batch carries on.
+func runBatch(opts options, bo batchOptions) error {
+	//----------------------------------------------------------------------
+	// Input
+	//----------------------------------------------------------------------
+	in := io.Reader(os.Stdin)
+	if bo.input != "-" {
+		f, err := os.Open(bo.input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	questions, err := readBatchQuestions(in, strings.EqualFold(filepath.Ext(bo.input), ".jsonl"))
+	if err != nil {
+		return fmt.Errorf("%s: %w", bo.input, err)
+	}
+	versions := make([]*string, len(questions))
+	for i := range questions {
+		versions[i] = &questions[i].Version
+	}
+	if err := resolveAutoVersion(&opts, versions...); err != nil {
+		return err
+	}
+	if err := resolveLanguage(&opts); err != nil {
+		return err
+	}
+
+	// Check every version up front, warning once per distinct value.
+	checked := map[string]string{}
+	for i := range questions {
+		v := cmp.Or(questions[i].Version, opts.version)
+		if _, ok := checked[v]; !ok {
+			version, err := docsVersion(v, opts)
+			if err != nil {
+				return fmt.Errorf("%s: %w", bo.input, err)
+			}
+			checked[v] = version
+		}
+		questions[i].Version = checked[v]
+	}
+
+	//----------------------------------------------------------------------
+	// Output
+	//----------------------------------------------------------------------
+	out := io.Writer(os.Stdout)
+	if bo.output != "" && bo.output != "-" {
+		f, err := os.Create(bo.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	report := reportFormatFor(bo.report, bo.output)
+	w := newBatchWriter(out, report, len(questions))
+
+	client, err := newAsker(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results, errs := askBatch(ctx, client, questions, opts, bo, w.add)
+	if err := w.close(); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	//----------------------------------------------------------------------
+	// Summary
+	//----------------------------------------------------------------------
+	var unanswered, failed int
+	for i, res := range results {
+		switch err := errs[i]; {
+		case err == nil:
+		case res.Answer == "" && (errors.Is(err, askdocs.ErrNoContent) || errors.Is(err, askdocs.ErrContentFiltered)):
+			unanswered++
+		default:
+			failed++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "answered %d of %d question(s)", len(results)-unanswered-failed, len(results))
+	if unanswered > 0 {
+		fmt.Fprintf(os.Stderr, ", %d without an answer", unanswered)
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, ", %d failed", failed)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	if failed > 0 {
+		return fmt.Errorf("%d question(s) failed", failed)
+	}
+	return nil
+}
+
+// askBatch asks the questions with at most bo.concurrency in flight and at
+// most bo.rate started per second. done is called with each result as it
+// completes; the results and their errors are also returned in input order.
+func askBatch(ctx context.Context, client askdocs.Asker, questions []batchQuestion, opts options, bo batchOptions, done func(int, *askdocs.Result)) ([]*askdocs.Result, []error) {
+	results := make([]*askdocs.Result, len(questions))
+	errs := make([]error, len(questions))
+
+	var tick <-chan time.Time
+	if bo.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / bo.rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(bo.concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				q := askdocs.Query{Query: questions[i].Query, Version: questions[i].Version, Language: opts.language}
+
+				qctx, cancel := ctx, context.CancelFunc(func() {})
+				if opts.timeout > 0 {
+					qctx, cancel = context.WithTimeout(ctx, opts.timeout)
+				}
+				res, err := collectAnswer(qctx, client, q)
+				cancel()
+				if err != nil {
+					res.Error = err.Error()
+				}
+				results[i], errs[i] = res, err
+				done(i, res)
+			}
+		}()
+	}
+
+feed:
+	for i := range questions {
+		if tick != nil && i > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Questions never started because of Ctrl-C are left out.
+	var finished []*askdocs.Result
+	var finishedErrs []error
+	for i, res := range results {
+		if res != nil {
+			finished = append(finished, res)
+			finishedErrs = append(finishedErrs, errs[i])
+		}
+	}
+	return finished, finishedErrs
+}
+
+// batchWriter writes results in input order as they complete.
+type batchWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	report  string
+	pending map[int]*askdocs.Result
+	next    int
+	total   int
+	err     error
+}
+
+func newBatchWriter(w io.Writer, report string, total int) *batchWriter {
+	bw := &batchWriter{w: w, report: report, pending: map[int]*askdocs.Result{}, total: total}
+	if report == reportMarkdown {
+		_, bw.err = fmt.Fprintf(w, "# gh ask-docs batch report\n\n_%d question(s) · %s_\n", total, time.Now().Format("January 2, 2006 15:04 MST"))
+	}
+	return bw
+}
+
+// add records the result for question i and writes every result that is now
+// next in line.
+func (bw *batchWriter) add(i int, res *askdocs.Result) {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	bw.pending[i] = res
+	for {
+		res, ok := bw.pending[bw.next]
+		if !ok {
+			return
+		}
+		delete(bw.pending, bw.next)
+		bw.next++
+		if bw.err == nil {
+			bw.err = bw.write(res)
+		}
+	}
+}
+
+func (bw *batchWriter) write(res *askdocs.Result) error {
+	if bw.report == reportJSONL {
+		return json.NewEncoder(bw.w).Encode(res)
+	}
+
+	var md strings.Builder
+	fmt.Fprintf(&md, "\n## %s\n\n_%s_\n\n", strings.Join(strings.Fields(res.Query), " "), res.Version)
+	if res.Error != "" {
+		fmt.Fprintf(&md, "> ⚠️ %s\n", res.Error)
+	}
+	if answer := strings.TrimSpace(res.Answer); answer != "" {
+		if res.Error != "" {
+			md.WriteString("\n")
+		}
+		md.WriteString(answer)
+		md.WriteString("\n")
+	}
+	if len(res.Sources) > 0 {
+		md.WriteString("\n**Sources**\n\n")
+		for _, s := range res.Sources {
+			text := s.Title
+			if text == "" {
+				text = s.URL
+			}
+			fmt.Fprintf(&md, "- %s\n", askdocs.AutoLink(s.URL, text))
+		}
+	}
+	_, err := io.WriteString(bw.w, md.String())
+	return err
+}
+
+// close reports the first write error.
+func (bw *batchWriter) close() error {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	if bw.err != nil {
+		return bw.err
+	}
+	if len(bw.pending) > 0 {
+		return errors.New("batch ended with results out of order")
+	}
+	return nil
+}
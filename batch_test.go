@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+func TestReadBatchQuestions(t *testing.T) {
+	txt := "# FAQ\nHow do I fork?\n\n  What is a PR?  \n"
+	got, err := readBatchQuestions(strings.NewReader(txt), false)
+	if err != nil {
+		t.Fatalf("readBatchQuestions(txt) error: %v", err)
+	}
+	if len(got) != 2 || got[0].Query != "How do I fork?" || got[1].Query != "What is a PR?" {
+		t.Errorf("readBatchQuestions(txt) = %+v", got)
+	}
+
+	jsonl := `{"query":"How do I fork?"}` + "\n\n" + `{"query":"SAML?","version":"enterprise-server@3.17"}` + "\n"
+	got, err = readBatchQuestions(strings.NewReader(jsonl), true)
+	if err != nil {
+		t.Fatalf("readBatchQuestions(jsonl) error: %v", err)
+	}
+	if len(got) != 2 || got[1] != (batchQuestion{Query: "SAML?", Version: "enterprise-server@3.17"}) {
+		t.Errorf("readBatchQuestions(jsonl) = %+v", got)
+	}
+
+	for _, in := range []string{`{"query":""}`, `not json`} {
+		if _, err := readBatchQuestions(strings.NewReader(in), true); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("readBatchQuestions(%q) error = %v, want a line number", in, err)
+		}
+	}
+}
+
+func TestReportFormatFor(t *testing.T) {
+	tests := []struct{ report, output, want string }{
+		{"", "-", reportJSONL},
+		{"", "out.jsonl", reportJSONL},
+		{"", "report.md", reportMarkdown},
+		{reportJSONL, "report.md", reportJSONL},
+	}
+	for _, tt := range tests {
+		if got := reportFormatFor(tt.report, tt.output); got != tt.want {
+			t.Errorf("reportFormatFor(%q, %q) = %q, want %q", tt.report, tt.output, got, tt.want)
+		}
+	}
+}
+
+// newBatchServer answers every question except those containing "unknown",
+// which get NO_CONTENT_SIGNAL, and "broken", which get a 400. It records the
+// most requests seen in flight at once.
+func newBatchServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var inFlight, peak atomic.Int32
+	server, _ := newSearchServer(t, nil, func(payload map[string]string) (int, []string) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		switch {
+		case strings.Contains(payload["query"], "broken"):
+			return http.StatusBadRequest, nil
+		case strings.Contains(payload["query"], "unknown"):
+			return http.StatusOK, []string{`{"chunkType":"NO_CONTENT_SIGNAL"}`}
+		}
+		return http.StatusOK, []string{
+			`{"chunkType":"SOURCES","sources":[{"title":"Forks","url":"https://docs.github.com/forks"}]}`,
+			`{"chunkType":"MESSAGE_CHUNK","text":"Answer to ` + payload["query"] + ` for ` + payload["version"] + `"}`,
+		}
+	})
+	return server, &peak
+}
+
+func writeBatchFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunBatchJSONL(t *testing.T) {
+	server, peak := newBatchServer(t)
+	opts := defaultOptions()
+	opts.endpoint = server.URL
+	opts.retries = 0
+
+	var questions []string
+	for _, q := range []string{"q0", "q1", "unknown q2", "q3", "q4", "q5"} {
+		questions = append(questions, `{"query":"`+q+`"}`)
+	}
+	questions[3] = `{"query":"q3","version":"enterprise-cloud"}`
+	input := writeBatchFile(t, "questions.jsonl", strings.Join(questions, "\n"))
+
+	var err error
+	out := captureStdout(t, func() {
+		captureStderr(t, func() {
+			err = runBatch(opts, batchOptions{input: input, output: "-", concurrency: 2})
+		})
+	})
+	if err != nil {
+		t.Errorf("runBatch() error = %v, NO_CONTENT_SIGNAL should not fail the batch", err)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d requests in flight, want at most 2", p)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got %d result lines, want 6:\n%s", len(lines), out)
+	}
+	for i, line := range lines {
+		var res askdocs.Result
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if !strings.HasSuffix(res.Query, "q"+string(rune('0'+i))) {
+			t.Errorf("line %d is for %q, want input order", i, res.Query)
+		}
+		switch i {
+		case 2:
+			if res.Error != askdocs.ErrNoContent.Error() || res.Answer != "" {
+				t.Errorf("line 2 = %+v, want the no-content error", res)
+			}
+		case 3:
+			if res.Answer != "Answer to q3 for enterprise-cloud@latest" {
+				t.Errorf("line 3 answer = %q, want the per-question version", res.Answer)
+			}
+		default:
+			if res.Error != "" || res.Answer != "Answer to "+res.Query+" for free-pro-team@latest" || len(res.Sources) != 1 {
+				t.Errorf("line %d = %+v", i, res)
+			}
+		}
+	}
+}
+
+func TestRunBatchMarkdownReport(t *testing.T) {
+	server, _ := newBatchServer(t)
+	opts := defaultOptions()
+	opts.endpoint = server.URL
+	opts.retries = 0
+
+	input := writeBatchFile(t, "questions.txt", "# comment\nHow do I fork?\nbroken question\n")
+	output := filepath.Join(t.TempDir(), "report.md")
+
+	var err error
+	stderr := captureStderr(t, func() {
+		err = runBatch(opts, batchOptions{input: input, output: output, concurrency: 4, rate: 100})
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 question(s) failed") {
+		t.Errorf("runBatch() error = %v, want one failure", err)
+	}
+	if !strings.Contains(stderr, "answered 1 of 2 question(s), 1 failed") {
+		t.Errorf("summary = %q", stderr)
+	}
+
+	data, _ := os.ReadFile(output)
+	report := string(data)
+	fork := strings.Index(report, "## How do I fork?")
+	broken := strings.Index(report, "## broken question")
+	if !strings.HasPrefix(report, "# gh ask-docs batch report") || fork < 0 || broken < fork {
+		t.Errorf("report headings missing or out of order:\n%s", report)
+	}
+	for _, want := range []string{"Answer to How do I fork?", "- [Forks](https://docs.github.com/forks)", "> ⚠️ "} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestRunBatchOfflineNoMatch(t *testing.T) {
+	withTempCacheDir(t)
+	opts := defaultOptions()
+	opts.offline = true
+	opts.docsDir = writeDocsCheckout(t)
+
+	input := writeBatchFile(t, "questions.txt", "How do I fork?\nkubernetes\n")
+
+	var err error
+	stderr := captureStderr(t, func() {
+		captureStdout(t, func() {
+			err = runBatch(opts, batchOptions{input: input, output: "-", concurrency: 1})
+		})
+	})
+	if err != nil {
+		t.Errorf("runBatch() error = %v, a question without an offline match should not fail the batch", err)
+	}
+	if !strings.Contains(stderr, "answered 1 of 2 question(s), 1 without an answer") {
+		t.Errorf("summary = %q", stderr)
+	}
+}
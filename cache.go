@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// defaultCacheTTL is how long a cached answer is served without asking again.
+const defaultCacheTTL = 24 * time.Hour
+
+// cacheEntry is a cached answer to a question.
+type cacheEntry struct {
+	Key      string          `json:"key"`
+	Query    string          `json:"query"`
+	Version  string          `json:"version"`
+	Language string          `json:"language"`
+	CachedAt time.Time       `json:"cached_at"`
+	Result   *askdocs.Result `json:"result"`
+}
+
+// expired reports whether the entry is older than ttl.
+func (e *cacheEntry) expired(ttl time.Duration) bool {
+	return time.Since(e.CachedAt) > ttl
+}
+
+// normalizeQuery makes trivially different spellings of a question share a
+// cache entry: case, surrounding space and repeated whitespace are ignored.
+func normalizeQuery(q string) string {
+	return strings.ToLower(strings.Join(strings.Fields(q), " "))
+}
+
+// queryLanguage returns the language a query is answered in.
+func queryLanguage(q askdocs.Query) string {
+	if q.Language == "" {
+		return "en"
+	}
+	return q.Language
+}
+
+// cacheKey identifies the answer to q: normalized query, version and language.
+func cacheKey(q askdocs.Query) string {
+	sum := sha256.Sum256([]byte(normalizeQuery(q.Query) + "\x00" + q.Version + "\x00" + queryLanguage(q)))
+	return hex.EncodeToString(sum[:])
+}
+
+func answerCacheDir() (string, error) {
+	dir, err := userCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gh-ask-docs", "answers"), nil
+}
+
+// loadCachedAnswer returns the cached answer to q, expired or not.
+func loadCachedAnswer(q askdocs.Query) (*cacheEntry, error) {
+	dir, err := answerCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return readCacheEntry(filepath.Join(dir, cacheKey(q)+".json"))
+}
+
+func readCacheEntry(path string) (*cacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Result == nil {
+		return nil, errors.New("cached answer has no result")
+	}
+	if entry.Key != strings.TrimSuffix(filepath.Base(path), ".json") {
+		return nil, errors.New("cached answer's key does not match its file name")
+	}
+	return &entry, nil
+}
+
+// storeCachedAnswer saves a complete answer to q.
+func storeCachedAnswer(q askdocs.Query, res *askdocs.Result) error {
+	dir, err := answerCacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	entry := cacheEntry{
+		Key:      cacheKey(q),
+		Query:    q.Query,
+		Version:  q.Version,
+		Language: queryLanguage(q),
+		CachedAt: time.Now(),
+		Result:   res,
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write then rename so a concurrent reader never sees half an entry.
+	tmp, err := os.CreateTemp(dir, entry.Key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, entry.Key+".json"))
+}
+
+// listCachedAnswers returns every cached answer, newest first. Unreadable
+// files are skipped.
+func listCachedAnswers() ([]*cacheEntry, error) {
+	paths, err := cacheFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*cacheEntry
+	for _, path := range paths {
+		if entry, err := readCacheEntry(path); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CachedAt.After(entries[j].CachedAt)
+	})
+	return entries, nil
+}
+
+// clearAnswerCache removes every cached answer and returns how many there were.
+func clearAnswerCache() (int, error) {
+	paths, err := cacheFiles()
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			return 0, err
+		}
+	}
+	return len(paths), nil
+}
+
+// pruneAnswerCache removes answers older than ttl, and files that cannot be
+// read, and returns how many were removed.
+func pruneAnswerCache(ttl time.Duration) (int, error) {
+	paths, err := cacheFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range paths {
+		entry, err := readCacheEntry(path)
+		if err == nil && !entry.expired(ttl) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// cacheFiles lists the entry files in the answer cache.
+func cacheFiles() ([]string, error) {
+	dir, err := answerCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return paths, err
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+func TestCacheKey(t *testing.T) {
+	base := askdocs.Query{Query: "How do I fork a repo?", Version: "free-pro-team@latest"}
+
+	same := []askdocs.Query{
+		{Query: "how do i fork a repo?", Version: "free-pro-team@latest"},
+		{Query: "  How do I   fork a repo?\n", Version: "free-pro-team@latest"},
+		{Query: "How do I fork a repo?", Version: "free-pro-team@latest", Language: "en"},
+	}
+	for _, q := range same {
+		if cacheKey(q) != cacheKey(base) {
+			t.Errorf("cacheKey(%+v) should equal cacheKey(%+v)", q, base)
+		}
+	}
+
+	different := []askdocs.Query{
+		{Query: "How do I fork a gist?", Version: "free-pro-team@latest"},
+		{Query: "How do I fork a repo?", Version: "enterprise-server@3.19"},
+		{Query: "How do I fork a repo?", Version: "free-pro-team@latest", Language: "ja"},
+	}
+	for _, q := range different {
+		if cacheKey(q) == cacheKey(base) {
+			t.Errorf("cacheKey(%+v) should differ from cacheKey(%+v)", q, base)
+		}
+	}
+}
+
+func TestStoreAndLoadCachedAnswer(t *testing.T) {
+	withTempCacheDir(t)
+	q := askdocs.Query{Query: "q", Version: "free-pro-team@latest"}
+
+	if _, err := loadCachedAnswer(q); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("loadCachedAnswer() on empty cache error = %v, want ErrNotExist", err)
+	}
+
+	res := &askdocs.Result{
+		Query:   "q",
+		Version: "free-pro-team@latest",
+		Answer:  "Use forks.",
+		Sources: []askdocs.Source{{Title: "Forks", URL: "https://docs.github.com/forks"}},
+	}
+	if err := storeCachedAnswer(q, res); err != nil {
+		t.Fatalf("storeCachedAnswer() error: %v", err)
+	}
+
+	entry, err := loadCachedAnswer(askdocs.Query{Query: " Q ", Version: "free-pro-team@latest"})
+	if err != nil {
+		t.Fatalf("loadCachedAnswer() error: %v", err)
+	}
+	if entry.Result.Answer != "Use forks." || len(entry.Result.Sources) != 1 {
+		t.Errorf("loaded result = %+v", entry.Result)
+	}
+	if entry.Language != "en" {
+		t.Errorf("Language = %q, want %q", entry.Language, "en")
+	}
+	if entry.expired(time.Hour) || !entry.expired(0) {
+		t.Errorf("a fresh entry should expire only with a zero TTL")
+	}
+}
+
+func TestPruneAndClearAnswerCache(t *testing.T) {
+	dir := withTempCacheDir(t)
+
+	for _, q := range []string{"one", "two", "three"} {
+		if err := storeCachedAnswer(askdocs.Query{Query: q}, &askdocs.Result{Answer: q}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Age one entry and corrupt another.
+	old, err := loadCachedAnswer(askdocs.Query{Query: "one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.CachedAt = time.Now().Add(-48 * time.Hour)
+	data, _ := json.Marshal(old)
+	answers := filepath.Join(dir, "gh-ask-docs", "answers")
+	if err := os.WriteFile(filepath.Join(answers, old.Key+".json"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(answers, "broken.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(answers, "bad.json"), []byte(`{"result":{"query":"x"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := listCachedAnswers()
+	if err != nil {
+		t.Fatalf("listCachedAnswers() error: %v", err)
+	}
+	if len(entries) != 3 || entries[2].Query != "one" {
+		t.Errorf("listCachedAnswers() should skip broken files and sort newest first, got %d entries", len(entries))
+	}
+	out := captureStdout(t, func() {
+		root := newRootCmd(defaultOptions(), func(options) error { return nil })
+		root.SetArgs([]string{"cache", "ls"})
+		if err := root.Execute(); err != nil {
+			t.Errorf("cache ls error: %v", err)
+		}
+	})
+	if strings.Count(out, "\n") != 3 {
+		t.Errorf("cache ls = %q, want the 3 readable entries", out)
+	}
+
+	n, err := pruneAnswerCache(defaultCacheTTL)
+	if err != nil || n != 3 {
+		t.Errorf("pruneAnswerCache() = %d, %v; want 3 removed", n, err)
+	}
+
+	n, err = clearAnswerCache()
+	if err != nil || n != 2 {
+		t.Errorf("clearAnswerCache() = %d, %v; want 2 removed", n, err)
+	}
+	if entries, _ := listCachedAnswers(); len(entries) != 0 {
+		t.Errorf("cache should be empty after clear, has %d entries", len(entries))
+	}
+}
+
+func runAskJSON(t *testing.T, opts options) *askdocs.Result {
+	t.Helper()
+	var res askdocs.Result
+	out := captureStdout(t, func() {
+		if err := runAsk(opts); err != nil {
+			t.Errorf("runAsk() error: %v", err)
+		}
+	})
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	return &res
+}
+
+func TestRunAskUsesCache(t *testing.T) {
+	withTempCacheDir(t)
+	server, hits := newSearchServer(t, forkAnswer, nil)
+
+	opts := defaultOptions()
+	opts.query = "How do I fork?"
+	opts.format = formatJSON
+	opts.theme = "dark"
+	opts.endpoint = server.URL
+
+	if res := runAskJSON(t, opts); res.Cached || res.Answer != "Use forks." {
+		t.Errorf("first answer = %+v, want a fresh answer", res)
+	}
+
+	opts.query = "how do I  fork?"
+	res := runAskJSON(t, opts)
+	if !res.Cached || res.Answer != "Use forks." || len(res.Sources) != 1 {
+		t.Errorf("second answer = %+v, want the cached answer", res)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("server hit %d times, want 1", hits.Load())
+	}
+
+	opts.refresh = true
+	if res := runAskJSON(t, opts); res.Cached {
+		t.Error("--refresh should not serve the cached answer")
+	}
+	opts.refresh = false
+	opts.noCache = true
+	if res := runAskJSON(t, opts); res.Cached {
+		t.Error("--no-cache should not serve the cached answer")
+	}
+	opts.noCache = false
+	opts.conversationID = "conv-1"
+	if res := runAskJSON(t, opts); res.Cached {
+		t.Error("follow-up questions should not be served from the cache")
+	}
+	if hits.Load() != 4 {
+		t.Errorf("server hit %d times, want 4", hits.Load())
+	}
+}
+
+func TestRunAskCachedKeepsConversation(t *testing.T) {
+	withTempCacheDir(t)
+	t.Setenv("GH_ASK_DOCS_SESSION", "test")
+	server, _ := newSearchServer(t, forkAnswer, nil)
+
+	q := askdocs.Query{Query: "How do I fork?", Version: "free-pro-team@latest", Language: "en"}
+	if err := storeCachedAnswer(q, &askdocs.Result{Query: q.Query, Version: q.Version, Answer: "Use forks.", ConversationID: "conv-elsewhere"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveConversation(conversationState{ConversationID: "conv-mine", Version: q.Version}); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := defaultOptions()
+	opts.query = q.Query
+	opts.language = q.Language
+	opts.format = formatJSON
+	opts.theme = "dark"
+	opts.endpoint = server.URL
+	if res := runAskJSON(t, opts); !res.Cached || res.ConversationID != "" {
+		t.Errorf("answer = %+v, want the cached answer without its conversation", res)
+	}
+	if state, err := loadConversation(); err != nil || state.ConversationID != "conv-mine" {
+		t.Errorf("conversation = %+v, %v; a cache hit should not replace it", state, err)
+	}
+}
+
+func TestRunAskServesStaleAnswerOffline(t *testing.T) {
+	withTempCacheDir(t)
+	server, _ := newSearchServer(t, forkAnswer, nil)
+
+	opts := defaultOptions()
+	opts.query = "How do I fork?"
+	opts.format = formatJSON
+	opts.theme = "dark"
+	opts.endpoint = server.URL
+	opts.retries = 0
+	runAskJSON(t, opts)
+
+	server.Close()
+	opts.cacheTTL = time.Nanosecond
+
+	var res *askdocs.Result
+	stderr := captureStderr(t, func() { res = runAskJSON(t, opts) })
+	if !res.Cached || res.Answer != "Use forks." {
+		t.Errorf("offline answer = %+v, want the expired cached answer", res)
+	}
+	if !strings.Contains(stderr, "showing the answer cached") {
+		t.Errorf("stderr = %q, want a notice about the cached answer", stderr)
+	}
+}
+
+// captureStderr returns everything written to stderr while fn runs.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	orig := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+
+	w.Close()
+	os.Stderr = orig
+	return <-done
+}
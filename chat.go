@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+const chatPrompt = "ask-docs> "
+
+// chatCommands maps each slash command to its help text.
+var chatCommands = map[string]string{
+	"/version":  "show or change the docs version (/version enterprise-server@3.17, /version auto)",
+	"/language": "show or change the docs language (/language ja, /language auto)",
+	"/sources":  "show sources for the last answer (/sources on|off toggles auto display)",
+	"/clear":    "clear the screen and start a new conversation",
+	"/save":     "save the transcript as Markdown (/save [file])",
+	"/help":     "show this help",
+	"/exit":     "leave chat (also Ctrl-D)",
+}
+
+// chatTurn is a single question and answer in a chat session.
+type chatTurn struct {
+	Question string
+	Version  string
+	Answer   *askdocs.Result
+	AskedAt  time.Time
+}
+
+// chatSession holds the state of an interactive chat.
+type chatSession struct {
+	opts           options
+	client         askdocs.Asker
+	r              renderers
+	version        string
+	language       string
+	conversationID string
+	transcript     []chatTurn
+}
+
+// lineReader reads one line of user input at a time.
+type lineReader interface {
+	ReadLine() (string, error)
+}
+
+// runChat starts an interactive session on the current terminal.
+func runChat(opts options) error {
+	if opts.format != formatText {
+		return fmt.Errorf("chat only supports --format %s", formatText)
+	}
+
+	version, err := docsVersion(opts.version, opts)
+	if err != nil {
+		return err
+	}
+
+	r, err := newRenderers(opts.theme, opts.wrapWidth)
+	if err != nil {
+		return err
+	}
+
+	conversationID, err := resolveConversationID(opts, version)
+	if err != nil {
+		return err
+	}
+
+	client, err := newAsker(opts)
+	if err != nil {
+		return err
+	}
+
+	s := &chatSession{
+		opts:           opts,
+		client:         client,
+		r:              r,
+		version:        version,
+		language:       opts.language,
+		conversationID: conversationID,
+	}
+
+	lr := newLineReader()
+	if _, ok := lr.(*termLineReader); ok {
+		fmt.Printf("Asking GitHub Docs (%s). Type /help for commands, Ctrl-C to clear the line, Ctrl-D to exit.\n\n", s.version)
+	}
+
+	// A query given on the command line is asked first.
+	if opts.query != "" {
+		s.ask(opts.query)
+	}
+	return s.run(lr)
+}
+
+// run reads lines until EOF or /exit.
+func (s *chatSession) run(lr lineReader) error {
+	for {
+		line, err := lr.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "/") {
+			if quit := s.handleCommand(line); quit {
+				return nil
+			}
+			continue
+		}
+
+		s.ask(line)
+	}
+}
+
+// ask streams the answer to a question, continuing the current conversation.
+func (s *chatSession) ask(question string) {
+	// Ctrl-C stops the current answer but stays in chat
+	ctx, cancel := askContext(s.opts)
+	res, err := streamAnswer(ctx, s.client, askdocs.Query{
+		Query:          question,
+		Version:        s.version,
+		Language:       s.language,
+		ConversationID: s.conversationID,
+	}, s.opts, s.r)
+	cancel()
+	addToHistory(res, err, s.opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, askdocs.ErrorMessage(err))
+		return
+	}
+
+	if res.ConversationID != "" {
+		s.conversationID = res.ConversationID
+		if err := saveConversation(conversationState{ConversationID: res.ConversationID, Version: s.version}); err != nil && s.opts.debug {
+			fmt.Fprintf(os.Stderr, "could not save conversation: %v\n", err)
+		}
+	}
+
+	s.transcript = append(s.transcript, chatTurn{
+		Question: question,
+		Version:  s.version,
+		Answer:   res,
+		AskedAt:  time.Now(),
+	})
+
+	if s.opts.showSources {
+		printSources(res.Sources, s.opts.raw, s.r)
+	}
+	fmt.Println()
+}
+
+// handleCommand runs a slash command and reports whether chat should end.
+func (s *chatSession) handleCommand(line string) (quit bool) {
+	fields := strings.Fields(line)
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "/exit", "/quit":
+		return true
+
+	case "/help":
+		names := make([]string, 0, len(chatCommands))
+		for name := range chatCommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("  %-10s %s\n", name, chatCommands[name])
+		}
+
+	case "/version":
+		if len(args) > 0 && args[0] == versionAuto {
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			version, err := detectVersion(ctx, githubHost())
+			cancel()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, askdocs.ErrorMessage(err))
+				break
+			}
+			s.version = version
+		} else if len(args) > 0 {
+			version, err := docsVersion(args[0], s.opts)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, askdocs.ErrorMessage(err))
+				break
+			}
+			s.version = version
+		}
+		fmt.Printf("version: %s\n", s.version)
+
+	case "/language":
+		if len(args) > 0 {
+			opts := s.opts
+			opts.language = args[0]
+			if err := resolveLanguage(&opts); err != nil {
+				fmt.Fprintln(os.Stderr, askdocs.ErrorMessage(err))
+				break
+			}
+			s.language = opts.language
+		}
+		fmt.Printf("language: %s\n", s.language)
+
+	case "/sources":
+		switch {
+		case len(args) > 0 && args[0] == "on":
+			s.opts.showSources = true
+			fmt.Println("sources will be shown after each answer")
+		case len(args) > 0 && args[0] == "off":
+			s.opts.showSources = false
+			fmt.Println("sources will be hidden")
+		case len(s.transcript) == 0:
+			fmt.Println("no answer yet")
+		default:
+			last := s.transcript[len(s.transcript)-1].Answer
+			if len(last.Sources) == 0 {
+				fmt.Println("the last answer had no sources")
+			}
+			printSources(last.Sources, s.opts.raw, s.r)
+		}
+
+	case "/clear":
+		fmt.Print("\033[H\033[2J")
+		s.conversationID = ""
+		s.transcript = nil
+
+	case "/save":
+		path := fmt.Sprintf("gh-ask-docs-chat-%s.md", time.Now().Format("20060102-150405"))
+		if len(args) > 0 {
+			path = args[0]
+		}
+		if err := os.WriteFile(path, []byte(s.transcriptMarkdown()), 0o600); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			break
+		}
+		fmt.Printf("saved %d answer(s) to %s\n", len(s.transcript), path)
+
+	default:
+		fmt.Printf("unknown command %s (try /help)\n", cmd)
+	}
+	return false
+}
+
+// transcriptMarkdown renders every question and answer of the session.
+func (s *chatSession) transcriptMarkdown() string {
+	var md strings.Builder
+	md.WriteString("# gh ask-docs chat\n")
+	for _, turn := range s.transcript {
+		fmt.Fprintf(&md, "\n## %s\n\n", turn.Question)
+		fmt.Fprintf(&md, "_%s · %s_\n\n", turn.Version, turn.AskedAt.Format(time.RFC1123))
+		md.WriteString(strings.TrimSpace(turn.Answer.Answer))
+		md.WriteString("\n")
+		if len(turn.Answer.Sources) > 0 {
+			md.WriteString("\n**Sources**\n\n")
+			for _, src := range turn.Answer.Sources {
+				text := src.Title
+				if text == "" {
+					text = src.URL
+				}
+				fmt.Fprintf(&md, "- %s\n", askdocs.AutoLink(src.URL, text))
+			}
+		}
+	}
+	return md.String()
+}
+
+// newLineReader returns a line-editing reader with history when stdin is a
+// terminal, and a plain line scanner otherwise.
+func newLineReader() lineReader {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return &scanLineReader{scanner: bufio.NewScanner(os.Stdin)}
+	}
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{&ctrlCReader{r: os.Stdin}, os.Stdout}, chatPrompt)
+	t.AutoCompleteCallback = completeCommand
+	return &termLineReader{fd: fd, t: t}
+}
+
+// termLineReader switches the terminal to raw mode only while a line is
+// being edited so streamed answers render normally.
+type termLineReader struct {
+	fd int
+	t  *term.Terminal
+}
+
+func (lr *termLineReader) ReadLine() (string, error) {
+	if w, h, err := term.GetSize(lr.fd); err == nil {
+		_ = lr.t.SetSize(w, h)
+	}
+
+	oldState, err := term.MakeRaw(lr.fd)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = term.Restore(lr.fd, oldState) }()
+
+	return lr.t.ReadLine()
+}
+
+// clearLine is what Ctrl-C is turned into while a line is edited: Ctrl-A
+// then Ctrl-K, moving to the start of the line and deleting to its end.
+var clearLine = []byte{0x01, 0x0b}
+
+// ctrlCReader turns Ctrl-C into clearLine, since term.Terminal would
+// otherwise return io.EOF and leave chat as Ctrl-D does.
+type ctrlCReader struct {
+	r       io.Reader
+	pending []byte
+}
+
+func (c *ctrlCReader) Read(p []byte) (int, error) {
+	if len(c.pending) == 0 {
+		buf := make([]byte, len(p))
+		n, err := c.r.Read(buf)
+		c.pending = bytes.ReplaceAll(buf[:n], []byte{0x03}, clearLine)
+		if len(c.pending) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+type scanLineReader struct {
+	scanner *bufio.Scanner
+}
+
+func (lr *scanLineReader) ReadLine() (string, error) {
+	if !lr.scanner.Scan() {
+		if err := lr.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return lr.scanner.Text(), nil
+}
+
+// completeCommand completes slash commands when Tab is pressed.
+func completeCommand(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' || !strings.HasPrefix(line, "/") || strings.Contains(line, " ") {
+		return "", 0, false
+	}
+
+	var matches []string
+	for name := range chatCommands {
+		if strings.HasPrefix(name, line) {
+			matches = append(matches, name)
+		}
+	}
+	if len(matches) != 1 {
+		return "", 0, false
+	}
+	return matches[0] + " ", len(matches[0]) + 1, true
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/term"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+func newTestChatSession(t *testing.T, endpoint string) *chatSession {
+	t.Helper()
+	withTempCacheDir(t)
+
+	client := askdocs.NewClient()
+	client.Endpoint = endpoint
+
+	r, err := newRenderers("dark", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &chatSession{
+		opts:    options{raw: true, format: formatText},
+		client:  client,
+		r:       r,
+		version: "free-pro-team@latest",
+	}
+}
+
+// silenceStdout discards stdout for the duration of the test.
+func silenceStdout(t *testing.T) {
+	t.Helper()
+	orig := os.Stdout
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = devNull
+	t.Cleanup(func() {
+		os.Stdout = orig
+		devNull.Close()
+	})
+}
+
+type sliceLineReader struct {
+	lines []string
+}
+
+func (lr *sliceLineReader) ReadLine() (string, error) {
+	if len(lr.lines) == 0 {
+		return "", io.EOF
+	}
+	line := lr.lines[0]
+	lr.lines = lr.lines[1:]
+	return line, nil
+}
+
+func TestChatSessionRun(t *testing.T) {
+	silenceStdout(t)
+
+	var payloads []map[string]string
+	server, _ := newSearchServer(t, nil, func(payload map[string]string) (int, []string) {
+		payloads = append(payloads, payload)
+		return http.StatusOK, []string{
+			`{"chunkType":"CONVERSATION_ID","conversation_id":"conv-1"}`,
+			`{"chunkType":"MESSAGE_CHUNK","text":"Answer to ` + payload["query"] + `"}`,
+			`{"chunkType":"SOURCES","sources":[{"title":"Docs","url":"https://docs.github.com/en"}]}`,
+		}
+	})
+	s := newTestChatSession(t, server.URL)
+
+	err := s.run(&sliceLineReader{lines: []string{
+		"How do I fork?",
+		"",
+		"/version enterprise-cloud",
+		"and for enterprise?",
+		"/clear",
+		"fresh start",
+		"/exit",
+		"never asked",
+	}})
+	if err != nil {
+		t.Fatalf("run() error: %v", err)
+	}
+
+	if len(payloads) != 3 {
+		t.Fatalf("server received %d questions, want 3", len(payloads))
+	}
+
+	if _, ok := payloads[0]["conversation_id"]; ok {
+		t.Error("first question should start a new conversation")
+	}
+	if payloads[1]["conversation_id"] != "conv-1" {
+		t.Errorf("follow-up conversation_id = %q, want %q", payloads[1]["conversation_id"], "conv-1")
+	}
+	if payloads[1]["version"] != "enterprise-cloud@latest" {
+		t.Errorf("follow-up version = %q, want %q", payloads[1]["version"], "enterprise-cloud@latest")
+	}
+	if _, ok := payloads[2]["conversation_id"]; ok {
+		t.Error("/clear should start a new conversation")
+	}
+	if len(s.transcript) != 1 || s.transcript[0].Question != "fresh start" {
+		t.Errorf("transcript after /clear = %+v", s.transcript)
+	}
+}
+
+func TestChatHandleCommand(t *testing.T) {
+	silenceStdout(t)
+	s := newTestChatSession(t, "http://127.0.0.1:0")
+
+	if quit := s.handleCommand("/exit"); !quit {
+		t.Error("/exit should end the session")
+	}
+	if quit := s.handleCommand("/quit"); !quit {
+		t.Error("/quit should end the session")
+	}
+	if quit := s.handleCommand("/nope"); quit {
+		t.Error("unknown commands should not end the session")
+	}
+
+	s.handleCommand("/version enterprise-server@3.17")
+	if s.version != "enterprise-server@3.17" {
+		t.Errorf("version = %q, want %q", s.version, "enterprise-server@3.17")
+	}
+
+	s.handleCommand("/sources on")
+	if !s.opts.showSources {
+		t.Error("/sources on should enable sources")
+	}
+	s.handleCommand("/sources off")
+	if s.opts.showSources {
+		t.Error("/sources off should disable sources")
+	}
+
+	s.conversationID = "conv-1"
+	s.transcript = []chatTurn{{Question: "q", Answer: &askdocs.Result{}}}
+	s.handleCommand("/clear")
+	if s.conversationID != "" || len(s.transcript) != 0 {
+		t.Error("/clear should reset the conversation and transcript")
+	}
+}
+
+func TestChatSave(t *testing.T) {
+	silenceStdout(t)
+	s := newTestChatSession(t, "http://127.0.0.1:0")
+	s.transcript = []chatTurn{
+		{
+			Question: "How do I fork?",
+			Version:  "free-pro-team@latest",
+			Answer: &askdocs.Result{
+				Answer:  "Click **Fork**.\n",
+				Sources: []askdocs.Source{{Title: "Fork a repo", URL: "https://docs.github.com/fork"}},
+			},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "chat.md")
+	s.handleCommand("/save " + path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("transcript not written: %v", err)
+	}
+	for _, want := range []string{
+		"## How do I fork?",
+		"_free-pro-team@latest",
+		"Click **Fork**.",
+		"- [Fork a repo](https://docs.github.com/fork)",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("transcript missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestCompleteCommand(t *testing.T) {
+	tests := []struct {
+		line   string
+		key    rune
+		want   string
+		wantOK bool
+	}{
+		{"/ver", '\t', "/version ", true},
+		{"/sa", '\t', "/save ", true},
+		{"/s", '\t', "", false}, // ambiguous
+		{"/ver", 'x', "", false},
+		{"question", '\t', "", false},
+		{"/version 3", '\t', "", false},
+	}
+
+	for _, tt := range tests {
+		got, pos, ok := completeCommand(tt.line, len(tt.line), tt.key)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("completeCommand(%q, %q) = %q, %v; want %q, %v", tt.line, tt.key, got, ok, tt.want, tt.wantOK)
+		}
+		if ok && pos != len(got) {
+			t.Errorf("completeCommand(%q) pos = %d, want %d", tt.line, pos, len(got))
+		}
+	}
+}
+
+func TestCtrlCClearsLine(t *testing.T) {
+	tm := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{&ctrlCReader{r: strings.NewReader("draft\x02\x02\x03/help\r")}, io.Discard}, chatPrompt)
+
+	line, err := tm.ReadLine()
+	if err != nil || line != "/help" {
+		t.Errorf("ReadLine() = %q, %v; want Ctrl-C to clear the draft, not exit", line, err)
+	}
+	if _, err := tm.ReadLine(); err != io.EOF {
+		t.Errorf("ReadLine() at end of input error = %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,585 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// errMissingQuery is returned when there is nothing to ask.
+var errMissingQuery = errors.New("a question is required (see --help)")
+
+// newRootCmd builds the command tree. Flag defaults come from base, so values
+// from config files and the environment apply unless a flag overrides them.
+// run is called with the final options for every question asked through the
+// root command, `ask` or `chat`.
+func newRootCmd(base options, run func(options) error) *cobra.Command {
+	opts := base
+
+	question := func(chat bool) func(*cobra.Command, []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			opts.query = strings.Join(args, " ")
+			opts.chat = opts.chat || chat
+			if opts.rerun != 0 {
+				if err := applyRerun(&opts, cmd.Flags().Changed("version"), cmd.Flags().Changed("language")); err != nil {
+					return err
+				}
+			}
+			if opts.about != "" {
+				if err := applyAboutConfig(&opts, cmd.Flags().Changed("version"), cmd.Flags().Changed("language")); err != nil {
+					return err
+				}
+			}
+			return run(opts)
+		}
+	}
+
+	root := &cobra.Command{
+		Use:   "gh-ask-docs [flags] <query>",
+		Short: "Ask the docs.github.com AI search about GitHub",
+		Long: "Ask the LLM at docs.github.com questions about GitHub, answered from the docs.\n\n" +
+			"Use -- to end flags when the question starts with a dash or a command name.\n" +
+			"Use - to read the question from stdin, --file to read it from a file or\n" +
+			"--editor to write it in $EDITOR. --context - appends piped input to the\n" +
+			"question as context.",
+		Example: `  gh ask-docs "How do I create a pull request?"
+  gh ask-docs --file question.md
+  gh run view --log-failed | gh ask-docs --context - "Why did this fail?"
+  gh ask-docs --about .github/workflows/ci.yml
+  gh ask-docs --version enterprise-server@3.17 --sources "How to configure SAML?"
+  gh ask-docs -- --force-with-lease vs --force`,
+		Args:          cobra.ArbitraryArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Annotations: map[string]string{
+			cobra.CommandDisplayNameAnnotation: "gh ask-docs",
+		},
+		RunE: question(false),
+	}
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return fmt.Errorf("%w\nRun '%s --help' for usage.", err, cmd.CommandPath())
+	})
+
+	f := root.PersistentFlags()
+	f.StringVar(&opts.version, "version", base.version, "docs version: free-pro-team, enterprise-cloud, enterprise-server@<version>, or auto to detect it from the gh host")
+	f.BoolVar(&opts.strictVersion, "strict-version", base.strictVersion, "fail instead of falling back when --version is unknown or unsupported")
+	f.StringVar(&opts.language, "language", base.language, "docs language: en, es, ja, pt, zh, ru, fr, ko, de, or auto to follow LANG/LC_ALL")
+	f.BoolVar(&opts.showSources, "sources", base.showSources, "show reference links after the answer")
+	f.BoolVar(&opts.raw, "no-render", base.raw, "stream raw Markdown without Glamour")
+	f.BoolVar(&opts.noStream, "no-stream", base.noStream, "don't stream the answer, print it only when complete")
+	f.IntVar(&opts.wrapWidth, "wrap", base.wrapWidth, "word-wrap width for rendered output (0 = no wrap)")
+	f.Var(newEnumValue(&opts.theme, base.theme, "auto", "light", "dark"), "theme", "color theme: auto, light, dark")
+	f.Var(newEnumValue(&opts.format, base.format, formatText, formatJSON, formatNDJSON), "format", "output format: text, json, ndjson")
+	f.StringVar(&opts.endpoint, "endpoint", base.endpoint, "AI Search API endpoint (env GH_ASK_DOCS_ENDPOINT)")
+	f.StringArrayVarP(&opts.headers, "header", "H", base.headers, "extra HTTP header \"Key: value\" for every request (repeatable)")
+	f.StringVar(&opts.clientName, "client-name", base.clientName, "client_name sent in the payload (default \""+askdocs.DefaultClientName+"\")")
+	f.DurationVar(&opts.timeout, "timeout", base.timeout, "give up after this long in total (0 = no limit)")
+	f.DurationVar(&opts.idleTimeout, "idle-timeout", base.idleTimeout, "give up when no data arrives for this long")
+	f.IntVar(&opts.retries, "retries", base.retries, "retries for connection errors, 429 and 5xx")
+	f.DurationVar(&opts.retryBackoff, "retry-backoff", base.retryBackoff, "delay before the first retry, doubled each time")
+	f.BoolVar(&opts.keepPartial, "keep-partial", base.keepPartial, "print a partial answer if the stream is interrupted")
+	f.BoolVar(&opts.debug, "debug", base.debug, "print raw NDJSON for troubleshooting")
+	f.BoolVarP(&opts.continueConv, "continue", "c", base.continueConv, "ask a follow-up in this session's last conversation")
+	f.StringVar(&opts.conversationID, "conversation", base.conversationID, "ask a follow-up in the given conversation")
+	f.BoolVar(&opts.noCache, "no-cache", base.noCache, "don't read or write the local answer cache")
+	f.BoolVar(&opts.refresh, "refresh", base.refresh, "ask again and replace the cached answer")
+	f.DurationVar(&opts.cacheTTL, "cache-ttl", base.cacheTTL, "how long cached answers are reused")
+	f.BoolVar(&opts.noHistory, "no-history", base.noHistory, "don't record this question in the history")
+	f.BoolVar(&opts.offline, "offline", base.offline, "answer with excerpts from the local docs index instead of docs.github.com (see `gh ask-docs index`)")
+	f.StringVar(&opts.docsDir, "docs-dir", base.docsDir, "github/docs checkout to build the offline index from")
+
+	root.Flags().BoolVarP(&opts.chat, "interactive", "i", base.chat, "start an interactive chat session")
+	root.Flags().BoolVar(&opts.listVersions, "list-versions", base.listVersions, "list supported enterprise server versions")
+	_ = root.Flags().MarkDeprecated("list-versions", "use `gh ask-docs versions` instead")
+	addAskFlags(root.Flags(), &opts, base)
+
+	_ = root.RegisterFlagCompletionFunc("version", completeVersions)
+	_ = root.RegisterFlagCompletionFunc("language", completeLanguages)
+	_ = root.RegisterFlagCompletionFunc("theme", cobra.FixedCompletions([]string{"auto", "light", "dark"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = root.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{formatText, formatJSON, formatNDJSON}, cobra.ShellCompDirectiveNoFileComp))
+	_ = root.RegisterFlagCompletionFunc("export-format", cobra.FixedCompletions([]string{exportMarkdown, exportHTML, exportJSON}, cobra.ShellCompDirectiveNoFileComp))
+
+	ask := &cobra.Command{
+		Use:   "ask <query>",
+		Short: "Ask a question (the default command)",
+		RunE:  question(false),
+	}
+	addAskFlags(ask.Flags(), &opts, base)
+
+	root.AddCommand(
+		ask,
+		&cobra.Command{
+			Use:   "chat [query]",
+			Short: "Start an interactive chat session",
+			RunE:  question(true),
+		},
+		newVersionsCmd(&opts),
+		newConfigCmd(),
+		newCacheCmd(&opts),
+		newHistoryCmd(&opts),
+		newExportCmd(),
+		newBatchCmd(&opts),
+		newExplainRunCmd(&opts),
+		newIndexCmd(&opts),
+	)
+	for _, cmd := range root.Commands() {
+		hintQuestion(cmd)
+	}
+	return root
+}
+
+// hintQuestion wraps the argument checks of cmd and its subcommands so that
+// a question starting with a command name, such as `gh ask-docs cache
+// dependencies in actions`, fails with a hint to use -- rather than only the
+// usage error.
+func hintQuestion(cmd *cobra.Command) {
+	if check := cmd.Args; check != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			err := check(cmd, args)
+			if err == nil || len(args) == 0 {
+				return err
+			}
+			words := strings.Fields(cmd.CommandPath())[len(strings.Fields(cmd.Root().CommandPath())):]
+			question := strings.Join(append(words, args...), " ")
+			return fmt.Errorf("%w\nTo ask a question starting with %q, use: gh ask-docs -- %s", err, words[0], question)
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		hintQuestion(sub)
+	}
+}
+
+// requireConfig makes every command but config and help fail with cfgErr,
+// the error reading the config files, instead of running with the built-in
+// defaults. A broken config file must not stop `config set` from fixing it.
+func requireConfig(root *cobra.Command, cfgErr error) {
+	if cfgErr == nil {
+		return
+	}
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		for c := cmd; c != nil; c = c.Parent() {
+			if c.Name() == "config" || c.Name() == "help" {
+				return nil
+			}
+		}
+		return cfgErr
+	}
+}
+
+// addAskFlags adds the flags that only apply to a single question, shared by
+// the root command and `ask`.
+func addAskFlags(f *pflag.FlagSet, opts *options, base options) {
+	f.IntVar(&opts.rerun, "rerun", base.rerun, "ask the question from a history entry again")
+	f.StringVarP(&opts.file, "file", "f", base.file, "read the question from a file (- for stdin)")
+	f.BoolVar(&opts.editor, "editor", base.editor, "write the question in $EDITOR")
+	f.StringVar(&opts.context, "context", base.context, "append a file, or - for stdin, to the question as context")
+	f.StringVar(&opts.about, "about", base.about, "ask about a workflow, action.yml, dependabot.yml, CODEOWNERS or other repository file")
+	f.StringVarP(&opts.output, "output", "o", base.output, "also write the answer to a .md, .html or .json file")
+	f.Var(newEnumValue(&opts.exportFormat, base.exportFormat, exportMarkdown, exportHTML, exportJSON), "export-format", "format for --output: markdown, html, json (default: from the file extension)")
+	f.StringVar(&opts.comment, "comment", base.comment, "post the answer as a comment on owner/repo#number (issue or pull request)")
+	f.BoolVar(&opts.dryRun, "dry-run", base.dryRun, "with --comment, print the comment instead of posting it")
+	f.StringSliceVar(&opts.compare, "compare", base.compare, "ask each of these comma-separated versions and compare the answers")
+}
+
+// newVersionsCmd builds `versions [refresh]`.
+func newVersionsCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "versions",
+		Short: "List docs versions and GHES release dates",
+		Long: "List the docs versions, with the release and deprecation dates of each\n" +
+			"GitHub Enterprise Server release; releases deprecated within 90 days are\n" +
+			"flagged. The list is built in; `versions refresh` fetches the current one\n" +
+			"from github/docs. Use --format json for scripts.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVersions(*opts)
+		},
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "refresh",
+		Short: "Fetch the supported versions from github/docs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer cancel()
+			versions, err := refreshVersions(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Supported GitHub Enterprise Server versions: %s (latest %s)\n", strings.Join(versions.SupportedVersions, ", "), versions.LatestVersion)
+			return nil
+		},
+	})
+	return cmd
+}
+
+// newExportCmd builds `export <history-id>`.
+func newExportCmd() *cobra.Command {
+	var output, format string
+	cmd := &cobra.Command{
+		Use:   "export <history-id>",
+		Short: "Write a recorded answer to a Markdown, HTML or JSON file",
+		Long: "Write a recorded answer as a self-contained file: the question as a heading,\n" +
+			"the answer and its sources, with the version and date as front matter.\n" +
+			"The format follows the --output extension unless --export-format is given.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseHistoryID(args[0])
+			if err != nil {
+				return err
+			}
+			e, err := findHistory(id)
+			if err != nil {
+				return err
+			}
+			return exportToFile(exportFromHistory(e), output, format)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "-", "file to write, or - for stdout")
+	cmd.Flags().Var(newEnumValue(&format, "", exportMarkdown, exportHTML, exportJSON), "export-format", "markdown, html or json (default: from the file extension)")
+	_ = cmd.RegisterFlagCompletionFunc("export-format", cobra.FixedCompletions([]string{exportMarkdown, exportHTML, exportJSON}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// newBatchCmd builds `batch <file>`.
+func newBatchCmd(opts *options) *cobra.Command {
+	var bo batchOptions
+	cmd := &cobra.Command{
+		Use:   "batch <file>",
+		Short: "Answer every question in a .txt or .jsonl file",
+		Long: "Answer a list of questions: one per line in a .txt file (blank lines and\n" +
+			"# comments are skipped), or {\"query\": ..., \"version\": ...} objects in a .jsonl\n" +
+			"file. Use - to read questions from stdin. One result per question is written\n" +
+			"in input order as JSONL, or as a Markdown report with --report markdown or an\n" +
+			"--output ending in .md. Failed questions are recorded and the batch carries on;\n" +
+			"the exit status is non-zero if any request failed. Answers are not cached or\n" +
+			"recorded in the history.",
+		Example: `  gh ask-docs batch questions.txt -o answers.jsonl
+  gh ask-docs batch --version enterprise-server@3.17 -p 2 --rate 1 faq.jsonl -o report.md`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if bo.concurrency < 1 {
+				return fmt.Errorf("--concurrency must be at least 1")
+			}
+			if bo.rate < 0 {
+				return fmt.Errorf("--rate must not be negative")
+			}
+			bo.input = args[0]
+			return runBatch(*opts, bo)
+		},
+	}
+	cmd.Flags().StringVarP(&bo.output, "output", "o", "-", "file to write, or - for stdout")
+	cmd.Flags().Var(newEnumValue(&bo.report, "", reportJSONL, reportMarkdown), "report", "jsonl or markdown (default: from the --output extension)")
+	cmd.Flags().IntVarP(&bo.concurrency, "concurrency", "p", 4, "questions asked at the same time")
+	cmd.Flags().Float64Var(&bo.rate, "rate", 2, "questions started per second (0 = no limit)")
+	_ = cmd.RegisterFlagCompletionFunc("report", cobra.FixedCompletions([]string{reportJSONL, reportMarkdown}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// newExplainRunCmd builds `explain-run <run>`.
+func newExplainRunCmd(opts *options) *cobra.Command {
+	var repo, job string
+	cmd := &cobra.Command{
+		Use:   "explain-run <run-id | run-url>",
+		Short: "Explain why a GitHub Actions workflow run failed",
+		Long: "Explain why a workflow run failed. The failed job's log and the failed step's\n" +
+			"definition are fetched with the same token as gh, the error lines are picked\n" +
+			"out of the log, and the docs are asked what went wrong. Sources are shown\n" +
+			"unless --sources=false is given; --debug also prints the question asked.",
+		Example: `  gh ask-docs explain-run 1234567890
+  gh ask-docs explain-run --repo octo/hello-world --job build 1234567890
+  gh ask-docs explain-run https://github.com/octo/hello-world/actions/runs/1234567890`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, runID, err := parseRunTarget(args[0], repo)
+			if err != nil {
+				return err
+			}
+			o := *opts
+			if !cmd.Flags().Changed("sources") {
+				o.showSources = true
+			}
+			return runExplainRun(o, r, runID, job)
+		},
+	}
+	cmd.Flags().StringVarP(&repo, "repo", "R", "", "repository of the run as [HOST/]OWNER/REPO (default: the current repository)")
+	cmd.Flags().StringVar(&job, "job", "", "name of the failed job to explain (default: the first)")
+	return cmd
+}
+
+// newIndexCmd builds `index [docs-dir]`.
+func newIndexCmd(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "index [<docs-dir>]",
+		Short: "Build the offline docs index from a github/docs checkout",
+		Long: "Index the Markdown articles in a local clone of github/docs for --offline,\n" +
+			"which answers with excerpts of the best-matching articles for the docs\n" +
+			"version instead of asking docs.github.com. Run it again after pulling the\n" +
+			"checkout. The checkout defaults to --docs-dir or the docs-dir setting.",
+		Example: `  git clone --depth 1 https://github.com/github/docs ~/src/docs
+  gh ask-docs index ~/src/docs
+  gh ask-docs --offline --version enterprise-server@3.17 "How do I configure SAML?"`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := opts.docsDir
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			return runIndex(dir)
+		},
+	}
+}
+
+// newCacheCmd builds `cache ls|clear|prune`.
+func newCacheCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage cached answers",
+		Long: "Answers to new questions are cached for --cache-ttl and reused when the same\n" +
+			"question is asked for the same version and language. When the API cannot be\n" +
+			"reached, an expired answer is shown instead of an error.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "ls",
+			Short: "List cached answers, newest first",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				entries, err := listCachedAnswers()
+				if err != nil {
+					return err
+				}
+				for _, e := range entries {
+					state := ""
+					if e.expired(opts.cacheTTL) {
+						state = " (expired)"
+					}
+					fmt.Printf("%s  %s  %-28s %s%s\n", e.Key[:12], e.CachedAt.Format("2006-01-02 15:04"), e.Version, e.Query, state)
+				}
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "clear",
+			Short: "Remove every cached answer",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				n, err := clearAnswerCache()
+				if err != nil {
+					return err
+				}
+				fmt.Printf("removed %d cached answer(s)\n", n)
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "prune",
+			Short: "Remove answers older than --cache-ttl",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				n, err := pruneAnswerCache(opts.cacheTTL)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("removed %d expired answer(s)\n", n)
+				return nil
+			},
+		},
+	)
+	return cmd
+}
+
+// newConfigCmd builds `config get|set|list`.
+func newConfigCmd() *cobra.Command {
+	keys := make([]string, len(configKeys))
+	var help strings.Builder
+	for i, k := range configKeys {
+		keys[i] = k.name
+		fmt.Fprintf(&help, "\n  %-9s %s (env %s)", k.name, k.help, k.env)
+	}
+	completeKey := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return keys, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage default settings",
+		Long: "Read and write defaults in ~/.config/gh-ask-docs/config.yml or the repository's " +
+			repoConfigName + ".\n\nKeys:" + help.String(),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	var repo bool
+	set := &cobra.Command{
+		Use:               "set <key> <value>",
+		Short:             "Save a default in the user (or --repo) config file",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeKey,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return configSet(args[0], args[1], repo)
+		},
+	}
+	set.Flags().BoolVar(&repo, "repo", false, "write "+repoConfigName+" instead of the user config")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:               "get <key>",
+			Short:             "Print a configured default",
+			Args:              cobra.ExactArgs(1),
+			ValidArgsFunction: completeKey,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return configGet(args[0])
+			},
+		},
+		set,
+		&cobra.Command{
+			Use:   "list",
+			Short: "Print every configured default",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return configList()
+			},
+		},
+	)
+	return cmd
+}
+
+// completeVersions offers the plans and supported enterprise server versions.
+func completeVersions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	versions := []string{versionAuto, "free-pro-team", "enterprise-cloud"}
+	if supported, err := askdocs.LoadSupportedVersions(); err == nil {
+		for _, v := range supported.SupportedVersions {
+			versions = append(versions, "enterprise-server@"+v)
+		}
+	}
+	return versions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeLanguages completes --language with the docs languages.
+func completeLanguages(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	languages := []string{languageAuto + "\tfollow LANG/LC_ALL"}
+	for _, l := range askdocs.Languages {
+		languages = append(languages, l.Code+"\t"+l.Name)
+	}
+	return languages, cobra.ShellCompDirectiveNoFileComp
+}
+
+// enumValue is a string flag restricted to a fixed set of values.
+type enumValue struct {
+	value   *string
+	allowed []string
+}
+
+func newEnumValue(p *string, def string, allowed ...string) *enumValue {
+	*p = def
+	return &enumValue{value: p, allowed: allowed}
+}
+
+func (e *enumValue) String() string { return *e.value }
+func (e *enumValue) Type() string   { return "string" }
+
+func (e *enumValue) Set(v string) error {
+	if !slices.Contains(e.allowed, v) {
+		return fmt.Errorf("must be one of %s", strings.Join(e.allowed, ", "))
+	}
+	*e.value = v
+	return nil
+}
+
+// newHistoryCmd builds `history [search|show|clear]`.
+func newHistoryCmd(opts *options) *cobra.Command {
+	var limit int
+	list := func(entries []historyEntry) error {
+		if opts.format == formatJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(entries)
+		}
+		printHistoryList(entries)
+		return nil
+	}
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List recent questions and answers",
+		Long: "Every question is recorded with its version, conversation ID, answer and\n" +
+			"sources (skip one with --no-history). Ask an entry again with --rerun <id>.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := loadHistory()
+			if err != nil {
+				return err
+			}
+			slices.Reverse(entries)
+			if limit > 0 && len(entries) > limit {
+				entries = entries[:limit]
+			}
+			return list(entries)
+		},
+	}
+	cmd.Flags().IntVarP(&limit, "limit", "L", 20, "number of entries to list (0 = all)")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "search <terms>...",
+			Short: "Find entries whose question or answer contains every term",
+			Args:  cobra.MinimumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				entries, err := loadHistory()
+				if err != nil {
+					return err
+				}
+				return list(searchHistory(entries, args))
+			},
+		},
+		&cobra.Command{
+			Use:   "show <id>",
+			Short: "Render a recorded answer",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				id, err := parseHistoryID(args[0])
+				if err != nil {
+					return err
+				}
+				e, err := findHistory(id)
+				if err != nil {
+					return err
+				}
+				if opts.format == formatJSON {
+					return list([]historyEntry{*e})
+				}
+				return showHistory(e, *opts)
+			},
+		},
+		&cobra.Command{
+			Use:   "clear",
+			Short: "Delete the history",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return clearHistory()
+			},
+		},
+	)
+	return cmd
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// commentTarget is the issue or pull request to comment on.
+type commentTarget struct {
+	Host   string
+	Owner  string
+	Repo   string
+	Number int
+}
+
+func (t commentTarget) String() string {
+	return fmt.Sprintf("%s/%s#%d", t.Owner, t.Repo, t.Number)
+}
+
+var commentTargetRe = regexp.MustCompile(`^([A-Za-z0-9_.-]+)/([A-Za-z0-9_.-]+)#([0-9]+)$`)
+
+// parseCommentTarget accepts owner/repo#number or the URL of an issue or
+// pull request.
+func parseCommentTarget(s string) (commentTarget, error) {
+	if m := commentTargetRe.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[3])
+		if n > 0 {
+			return commentTarget{Host: githubHost(), Owner: m[1], Repo: m[2], Number: n}, nil
+		}
+	}
+
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) >= 4 && (parts[2] == "issues" || parts[2] == "pull") {
+			if n, err := strconv.Atoi(parts[3]); err == nil && n > 0 {
+				return commentTarget{Host: u.Host, Owner: parts[0], Repo: parts[1], Number: n}, nil
+			}
+		}
+	}
+	return commentTarget{}, fmt.Errorf("invalid comment target %q: use owner/repo#number or an issue or pull request URL", s)
+}
+
+// commentBody is the Markdown posted for an answer: the answer, its sources
+// and a footer naming the docs version.
+func commentBody(res *askdocs.Result) string {
+	var md strings.Builder
+	md.WriteString(strings.TrimSpace(askdocs.StripANSI(res.Answer)))
+	md.WriteString("\n")
+	if len(res.Sources) > 0 {
+		md.WriteString("\n### Sources\n\n")
+		for _, s := range res.Sources {
+			text := s.Title
+			if text == "" {
+				text = s.URL
+			}
+			fmt.Fprintf(&md, "- %s\n", askdocs.AutoLink(s.URL, text))
+		}
+	}
+	fmt.Fprintf(&md, "\n---\n<sub>version: %s · answered from docs.github.com by gh ask-docs</sub>\n", res.Version)
+	return md.String()
+}
+
+// postComment posts the answer as a comment on target and returns its URL.
+// With dryRun set, it prints the request that would be made instead.
+func postComment(ctx context.Context, target commentTarget, res *askdocs.Result, dryRun bool) (string, error) {
+	body := commentBody(res)
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", target.Owner, target.Repo, target.Number)
+
+	if dryRun {
+		fmt.Fprintf(os.Stderr, "Would post to %s (POST %s%s):\n\n%s", target, githubAPIURL(target.Host), path, body)
+		return "", nil
+	}
+
+	client, err := newGitHubClient(target.Host)
+	if err != nil {
+		return "", err
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := client.do(ctx, "POST", path, map[string]string{"body": body}, &created); err != nil {
+		return "", fmt.Errorf("commenting on %s: %w", target, err)
+	}
+	return created.HTMLURL, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+func TestParseCommentTarget(t *testing.T) {
+	t.Setenv("GH_HOST", "")
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+
+	tests := []struct {
+		in   string
+		want commentTarget
+	}{
+		{"octo/hello-world#12", commentTarget{Host: "github.com", Owner: "octo", Repo: "hello-world", Number: 12}},
+		{"my.org/repo_1#3", commentTarget{Host: "github.com", Owner: "my.org", Repo: "repo_1", Number: 3}},
+		{"https://github.com/octo/hello/issues/7", commentTarget{Host: "github.com", Owner: "octo", Repo: "hello", Number: 7}},
+		{"https://ghes.example.com/octo/hello/pull/9/files", commentTarget{Host: "ghes.example.com", Owner: "octo", Repo: "hello", Number: 9}},
+	}
+	for _, tt := range tests {
+		got, err := parseCommentTarget(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseCommentTarget(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "octo/hello", "octo#1", "octo/hello#0", "octo/hello#x", "https://github.com/octo/hello/wiki/1"} {
+		if _, err := parseCommentTarget(in); err == nil {
+			t.Errorf("parseCommentTarget(%q) should fail", in)
+		}
+	}
+
+	t.Setenv("GH_HOST", "ghes.example.com")
+	if got, _ := parseCommentTarget("octo/hello#1"); got.Host != "ghes.example.com" {
+		t.Errorf("Host = %q, want GH_HOST", got.Host)
+	}
+}
+
+func TestCommentBody(t *testing.T) {
+	got := commentBody(&askdocs.Result{
+		Version: "enterprise-server@3.19",
+		Answer:  "\nClick **Fork**.\n\n",
+		Sources: []askdocs.Source{{Title: "Fork a repo", URL: "https://docs.github.com/fork"}},
+	})
+	want := "Click **Fork**.\n" +
+		"\n### Sources\n\n- [Fork a repo](https://docs.github.com/fork)\n" +
+		"\n---\n<sub>version: enterprise-server@3.19 · answered from docs.github.com by gh ask-docs</sub>\n"
+	if got != want {
+		t.Errorf("commentBody() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestGitHubToken(t *testing.T) {
+	orig := ghAuthToken
+	t.Cleanup(func() { ghAuthToken = orig })
+	ghAuthToken = func(host string) (string, error) { return "from-gh-" + host, nil }
+
+	tests := []struct {
+		name string
+		host string
+		env  map[string]string
+		want string
+	}{
+		{"gh fallback", "github.com", nil, "from-gh-github.com"},
+		{"GITHUB_TOKEN", "github.com", map[string]string{"GITHUB_TOKEN": "env-token"}, "env-token"},
+		{"GH_TOKEN wins", "github.com", map[string]string{"GH_TOKEN": "gh-env-token", "GITHUB_TOKEN": "env-token"}, "gh-env-token"},
+		{"GH_TOKEN not sent to GHES", "ghes.example.com", map[string]string{"GH_TOKEN": "gh-env-token"}, "from-gh-ghes.example.com"},
+		{"GH_ENTERPRISE_TOKEN", "ghes.example.com", map[string]string{"GH_TOKEN": "gh-env-token", "GH_ENTERPRISE_TOKEN": "enterprise-token"}, "enterprise-token"},
+		{"ghe.com uses GH_TOKEN", "octo.ghe.com", map[string]string{"GH_TOKEN": "gh-env-token", "GH_ENTERPRISE_TOKEN": "enterprise-token"}, "gh-env-token"},
+		{"ghe.com gh fallback", "octo.ghe.com", map[string]string{"GH_ENTERPRISE_TOKEN": "enterprise-token"}, "from-gh-octo.ghe.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+				t.Setenv(env, tt.env[env])
+			}
+			if got, _ := githubToken(tt.host); got != tt.want {
+				t.Errorf("githubToken(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubAPIURL(t *testing.T) {
+	tests := []struct {
+		host, override, want string
+	}{
+		{"github.com", "", "https://api.github.com"},
+		{"ghes.example.com", "", "https://ghes.example.com/api/v3"},
+		{"octo.ghe.com", "", "https://api.octo.ghe.com"},
+		{"Octo.GHE.com", "", "https://api.Octo.GHE.com"},
+		{"github.com", "http://127.0.0.1:9999/", "http://127.0.0.1:9999"},
+	}
+	for _, tt := range tests {
+		t.Setenv("GH_ASK_DOCS_GITHUB_API_URL", tt.override)
+		if got := githubAPIURL(tt.host); got != tt.want {
+			t.Errorf("githubAPIURL(%q) with override %q = %q, want %q", tt.host, tt.override, got, tt.want)
+		}
+	}
+}
+
+// newFakeGitHub serves the issue comments endpoint and records posted bodies.
+func newFakeGitHub(t *testing.T, status int) *[]string {
+	t.Helper()
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/octo/hello/issues/12/comments" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var payload struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		bodies = append(bodies, payload.Body)
+
+		w.WriteHeader(status)
+		if status == http.StatusCreated {
+			_, _ = w.Write([]byte(`{"html_url":"https://github.com/octo/hello/issues/12#issuecomment-1"}`))
+		} else {
+			_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("GH_ASK_DOCS_GITHUB_API_URL", server.URL)
+	t.Setenv("GH_HOST", "")
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	t.Setenv("GH_TOKEN", "test-token")
+	return &bodies
+}
+
+func TestPostComment(t *testing.T) {
+	bodies := newFakeGitHub(t, http.StatusCreated)
+	target, _ := parseCommentTarget("octo/hello#12")
+	res := &askdocs.Result{Version: "free-pro-team@latest", Answer: "Use forks."}
+
+	url, err := postComment(context.Background(), target, res, false)
+	if err != nil {
+		t.Fatalf("postComment() error: %v", err)
+	}
+	if url != "https://github.com/octo/hello/issues/12#issuecomment-1" {
+		t.Errorf("comment URL = %q", url)
+	}
+	if len(*bodies) != 1 || (*bodies)[0] != commentBody(res) {
+		t.Errorf("posted bodies = %q", *bodies)
+	}
+}
+
+func TestPostCommentDryRun(t *testing.T) {
+	bodies := newFakeGitHub(t, http.StatusCreated)
+	target, _ := parseCommentTarget("octo/hello#12")
+	res := &askdocs.Result{Version: "free-pro-team@latest", Answer: "Use forks."}
+
+	var url string
+	stderr := captureStderr(t, func() {
+		var err error
+		if url, err = postComment(context.Background(), target, res, true); err != nil {
+			t.Errorf("postComment() error: %v", err)
+		}
+	})
+	if url != "" || len(*bodies) != 0 {
+		t.Error("--dry-run should not post")
+	}
+	if !strings.Contains(stderr, "octo/hello#12") || !strings.Contains(stderr, commentBody(res)) {
+		t.Errorf("dry run output = %q, want the target and exact body", stderr)
+	}
+}
+
+func TestPostCommentError(t *testing.T) {
+	newFakeGitHub(t, http.StatusForbidden)
+	target, _ := parseCommentTarget("octo/hello#12")
+
+	_, err := postComment(context.Background(), target, &askdocs.Result{Answer: "a"}, false)
+	if err == nil || !strings.Contains(err.Error(), "Resource not accessible") {
+		t.Errorf("postComment() error = %v, want the API message", err)
+	}
+	if askdocs.ExitCode(err) != askdocs.ExitHTTPClientError {
+		t.Errorf("ExitCode = %d, want %d", askdocs.ExitCode(err), askdocs.ExitHTTPClientError)
+	}
+}
+
+func TestRunAskComment(t *testing.T) {
+	withTempCacheDir(t)
+	bodies := newFakeGitHub(t, http.StatusCreated)
+	server, _ := newSearchServer(t, forkAnswer, nil)
+
+	opts := defaultOptions()
+	opts.query = "How do I fork?"
+	opts.format = formatJSON
+	opts.theme = "dark"
+	opts.endpoint = server.URL
+	opts.comment = "https://github.com/octo/hello/issues/12"
+	captureStderr(t, func() { runAskJSON(t, opts) })
+
+	if len(*bodies) != 1 || !strings.Contains((*bodies)[0], "Use forks.") || !strings.Contains((*bodies)[0], "[Forks](https://docs.github.com/forks)") {
+		t.Errorf("posted bodies = %q", *bodies)
+	}
+
+	opts.comment = "not-a-target"
+	if err := runAsk(opts); err == nil {
+		t.Error("runAsk() with an invalid --comment target should fail before asking")
+	}
+}
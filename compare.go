@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// minCompareColumn is the narrowest column worth rendering side by side;
+// narrower terminals get one section per version instead.
+const minCompareColumn = 40
+
+// compareSeparator goes between the columns of a comparison.
+const compareSeparator = " │ "
+
+// compareResult is the --format json output of --compare.
+type compareResult struct {
+	Query   string            `json:"query"`
+	Answers []*askdocs.Result `json:"answers"`
+	Sources []sourceGroup     `json:"sources"`
+}
+
+// sourceGroup lists the sources cited by exactly the given versions.
+type sourceGroup struct {
+	Versions []string         `json:"versions"`
+	Sources  []askdocs.Source `json:"sources"`
+}
+
+// parseCompareVersions parses the --compare list, dropping duplicates.
+func parseCompareVersions(list []string, opts options) ([]string, error) {
+	var versions []string
+	seen := map[string]bool{}
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		v, err := docsVersion(v, opts)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+	if len(versions) < 2 {
+		return nil, errors.New("--compare needs at least two different versions, e.g. --compare free-pro-team,enterprise-server@3.16")
+	}
+	return versions, nil
+}
+
+// runCompare asks the question once per --compare version, concurrently, and
+// shows the answers together with a diff of their sources.
+func runCompare(opts options) error {
+	versions, err := parseCompareVersions(opts.compare, opts)
+	if err != nil {
+		return err
+	}
+	if opts.chat || opts.continueConv || opts.conversationID != "" {
+		return errors.New("--compare cannot be used for follow-up questions or chat")
+	}
+	if opts.output != "" || opts.comment != "" {
+		return errors.New("--compare cannot be combined with --output or --comment")
+	}
+
+	r, err := newRenderers(opts.theme, opts.wrapWidth)
+	if err != nil {
+		return err
+	}
+
+	//----------------------------------------------------------------------
+	// Ask every version at once
+	//----------------------------------------------------------------------
+	ctx, cancel := askContext(opts)
+	defer cancel()
+
+	client, err := newAsker(opts)
+	if err != nil {
+		return err
+	}
+
+	results := make([]*askdocs.Result, len(versions))
+	errs := make([]error, len(versions))
+	var wg sync.WaitGroup
+	for i, v := range versions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = compareAnswer(ctx, client, askdocs.Query{Query: opts.query, Version: v, Language: opts.language}, opts)
+		}()
+	}
+	if opts.format == formatText {
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		spin := time.NewTicker(100 * time.Millisecond)
+		for i := 0; ; i++ {
+			askdocs.RenderSpinner(askdocs.SpinnerFrames[i%len(askdocs.SpinnerFrames)])
+			select {
+			case <-spin.C:
+				continue
+			case <-done:
+			}
+			break
+		}
+		spin.Stop()
+		fmt.Fprint(os.Stderr, "\r \r")
+	}
+	wg.Wait()
+
+	var firstErr error
+	for i, res := range results {
+		if errs[i] != nil {
+			res.Error = askdocs.ErrorMessage(errs[i])
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", versions[i], errs[i])
+			}
+		}
+		addToHistory(res, errs[i], opts)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	//----------------------------------------------------------------------
+	// Output
+	//----------------------------------------------------------------------
+	groups := diffSources(results)
+	switch opts.format {
+	case formatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(compareResult{Query: opts.query, Answers: results, Sources: groups})
+	case formatNDJSON:
+		enc := json.NewEncoder(os.Stdout)
+		for _, res := range results {
+			_ = enc.Encode(res)
+		}
+	default:
+		printComparison(results, opts, r)
+		printSourceDiff(groups, len(results), opts.raw, r)
+	}
+	return firstErr
+}
+
+// compareAnswer returns the answer for one version, from the answer cache
+// when it is fresh.
+func compareAnswer(ctx context.Context, client askdocs.Asker, q askdocs.Query, opts options) (*askdocs.Result, error) {
+	if !opts.noCache && !opts.offline && !opts.refresh {
+		if entry, err := loadCachedAnswer(q); err == nil && !entry.expired(opts.cacheTTL) {
+			return cachedResult(entry), nil
+		}
+	}
+	res, err := collectAnswer(ctx, client, q)
+	if err == nil && !opts.noCache && !opts.offline {
+		if err := storeCachedAnswer(q, res); err != nil && opts.debug {
+			fmt.Fprintf(os.Stderr, "could not cache answer: %v\n", err)
+		}
+	}
+	return res, err
+}
+
+// compareMarkdown is the Markdown shown for one version's answer.
+func compareMarkdown(res *askdocs.Result) string {
+	md := "## " + res.Version + "\n\n"
+	if res.Error != "" {
+		md += "⚠️ *" + res.Error + "*\n"
+	}
+	return md + res.Answer
+}
+
+// printComparison prints the answers side by side when the terminal is wide
+// enough, otherwise one after the other.
+func printComparison(results []*askdocs.Result, opts options, r renderers) {
+	width := 0
+	if fd := int(os.Stdout.Fd()); term.IsTerminal(fd) {
+		width, _, _ = term.GetSize(fd)
+	}
+	if opts.wrapWidth > 0 && opts.wrapWidth < width {
+		width = opts.wrapWidth
+	}
+	column := (width - runewidth.StringWidth(compareSeparator)*(len(results)-1)) / len(results)
+
+	if opts.raw || column < minCompareColumn {
+		for i, res := range results {
+			if i > 0 {
+				fmt.Println()
+			}
+			md := compareMarkdown(res)
+			if opts.raw {
+				fmt.Println(strings.TrimRight(md, "\n"))
+				continue
+			}
+			out, _ := r.answer.Render(md)
+			fmt.Print(out)
+		}
+		return
+	}
+
+	cr, err := newRenderers(opts.theme, column)
+	if err != nil {
+		cr = r
+	}
+	columns := make([][]string, len(results))
+	for i, res := range results {
+		out, _ := cr.answer.Render(compareMarkdown(res))
+		columns[i] = strings.Split(strings.TrimRight(out, "\n"), "\n")
+	}
+	fmt.Print(sideBySide(columns, column))
+}
+
+// sideBySide joins rendered columns line by line, padding each to width.
+func sideBySide(columns [][]string, width int) string {
+	rows := 0
+	for _, col := range columns {
+		rows = max(rows, len(col))
+	}
+
+	var b strings.Builder
+	for row := range rows {
+		for i, col := range columns {
+			line := ""
+			if row < len(col) {
+				line = col[row]
+			}
+			if i == len(columns)-1 {
+				b.WriteString(strings.TrimRight(line, " "))
+				break
+			}
+			b.WriteString(line)
+			if pad := width - runewidth.StringWidth(askdocs.StripANSI(line)); pad > 0 {
+				b.WriteString(strings.Repeat(" ", pad))
+			}
+			b.WriteString(compareSeparator)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// diffSources groups the sources of the answered versions by which of them
+// cite each one: sources common to all come first, then the rest in order of
+// appearance.
+func diffSources(results []*askdocs.Result) []sourceGroup {
+	type cited struct {
+		source   askdocs.Source
+		versions []string
+	}
+	var order []string
+	byURL := map[string]*cited{}
+	answered := 0
+	for _, res := range results {
+		if res.Error != "" {
+			continue
+		}
+		answered++
+		for _, s := range res.Sources {
+			c, ok := byURL[s.URL]
+			if !ok {
+				c = &cited{source: s}
+				byURL[s.URL] = c
+				order = append(order, s.URL)
+			}
+			if !slices.Contains(c.versions, res.Version) {
+				c.versions = append(c.versions, res.Version)
+			}
+		}
+	}
+
+	var groups []sourceGroup
+	index := map[string]int{}
+	for _, url := range order {
+		c := byURL[url]
+		key := strings.Join(c.versions, ",")
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, sourceGroup{Versions: c.versions})
+		}
+		groups[i].Sources = append(groups[i].Sources, c.source)
+	}
+
+	for i, g := range groups {
+		if len(g.Versions) == answered && i > 0 {
+			copy(groups[1:i+1], groups[:i])
+			groups[0] = g
+			break
+		}
+	}
+	return groups
+}
+
+// printSourceDiff prints the groups from diffSources.
+func printSourceDiff(groups []sourceGroup, versions int, raw bool, r renderers) {
+	if len(groups) == 0 {
+		return
+	}
+
+	var md strings.Builder
+	md.WriteString("### Sources\n")
+	for _, g := range groups {
+		label := "Only in " + strings.Join(g.Versions, ", ")
+		if len(g.Versions) == versions {
+			label = "All versions"
+		} else if len(g.Versions) > 1 {
+			label = "In " + strings.Join(g.Versions, ", ")
+		}
+		fmt.Fprintf(&md, "\n**%s**\n\n", label)
+		for _, s := range g.Sources {
+			text := s.Title
+			if text == "" {
+				text = s.URL
+			}
+			fmt.Fprintf(&md, "* %s\n", askdocs.AutoLink(s.URL, text))
+		}
+	}
+
+	if raw {
+		fmt.Print("\n" + md.String())
+		return
+	}
+	out, _ := r.noWrap.Render(md.String())
+	fmt.Print(out)
+}
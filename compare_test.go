@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+func TestParseCompareVersions(t *testing.T) {
+	got, err := parseCompareVersions([]string{"free-pro-team", " enterprise-cloud ", "free-pro-team@latest", ""}, defaultOptions())
+	if err != nil {
+		t.Fatalf("parseCompareVersions() error: %v", err)
+	}
+	if want := []string{"free-pro-team@latest", "enterprise-cloud@latest"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCompareVersions() = %v, want %v", got, want)
+	}
+
+	for _, in := range [][]string{nil, {"free-pro-team"}, {"free-pro-team", "free-pro-team@latest"}} {
+		if _, err := parseCompareVersions(in, defaultOptions()); err == nil {
+			t.Errorf("parseCompareVersions(%v) should need two versions", in)
+		}
+	}
+}
+
+func TestDiffSources(t *testing.T) {
+	common := askdocs.Source{Title: "SAML", URL: "https://docs.github.com/saml"}
+	old := askdocs.Source{Title: "Old", URL: "https://docs.github.com/old"}
+	added := askdocs.Source{Title: "New", URL: "https://docs.github.com/new"}
+	results := []*askdocs.Result{
+		{Version: "a", Sources: []askdocs.Source{old, common}},
+		{Version: "b", Sources: []askdocs.Source{common, added}},
+		{Version: "c", Sources: []askdocs.Source{added, common}},
+		{Version: "d", Error: "failed"},
+	}
+
+	want := []sourceGroup{
+		{Versions: []string{"a", "b", "c"}, Sources: []askdocs.Source{common}},
+		{Versions: []string{"a"}, Sources: []askdocs.Source{old}},
+		{Versions: []string{"b", "c"}, Sources: []askdocs.Source{added}},
+	}
+	if got := diffSources(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffSources() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	got := sideBySide([][]string{{"one", "\x1b[1mtwo\x1b[0m", "three"}, {"uno  "}}, 5)
+	want := "one   │ uno\n" +
+		"\x1b[1mtwo\x1b[0m   │ \n" +
+		"three │ \n"
+	if got != want {
+		t.Errorf("sideBySide() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRunCompareJSON(t *testing.T) {
+	withTempCacheDir(t)
+	server, _ := newSearchServer(t, nil, func(payload map[string]string) (int, []string) {
+		if payload["version"] == "enterprise-cloud@latest" {
+			return http.StatusOK, []string{`{"chunkType":"NO_CONTENT_SIGNAL"}`}
+		}
+		return http.StatusOK, []string{
+			`{"chunkType":"SOURCES","sources":[{"title":"Forks","url":"https://docs.github.com/forks"}]}`,
+			`{"chunkType":"MESSAGE_CHUNK","text":"Answer for ` + payload["version"] + `"}`,
+		}
+	})
+
+	opts := defaultOptions()
+	opts.query = "How do I fork?"
+	opts.format = formatJSON
+	opts.theme = "dark"
+	opts.endpoint = server.URL
+	opts.compare = []string{"free-pro-team", "enterprise-server@latest", "enterprise-cloud"}
+
+	var err error
+	out := captureStdout(t, func() { err = runCompare(opts) })
+	if askdocs.ExitCode(err) != askdocs.ExitCode(askdocs.ErrNoContent) {
+		t.Errorf("runCompare() error = %v, want the no-content failure", err)
+	}
+
+	var got compareResult
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if len(got.Answers) != 3 || got.Answers[0].Answer != "Answer for free-pro-team@latest" || got.Answers[1].Answer != "Answer for enterprise-server@latest" {
+		t.Errorf("answers = %+v, want one per version in order", got.Answers)
+	}
+	if got.Answers[2].Error == "" {
+		t.Errorf("enterprise-cloud answer should carry its error: %+v", got.Answers[2])
+	}
+	if len(got.Sources) != 1 || len(got.Sources[0].Versions) != 2 {
+		t.Errorf("sources = %+v, want one group for the two answered versions", got.Sources)
+	}
+
+	opts.continueConv = true
+	if err := runCompare(opts); err == nil {
+		t.Error("--compare with --continue should fail")
+	}
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// repoConfigName is the per-repository config file, looked up from the
+// working directory up to the repository root.
+const repoConfigName = ".gh-ask-docs.yml"
+
+// config holds the defaults that can be set in a config file or the
+// environment. Unset fields leave the built-in default alone.
+type config struct {
+	Version  string `yaml:"version,omitempty"`
+	Language string `yaml:"language,omitempty"`
+	Theme    string `yaml:"theme,omitempty"`
+	Wrap     *int   `yaml:"wrap,omitempty"`
+	Sources  *bool  `yaml:"sources,omitempty"`
+	Format   string `yaml:"format,omitempty"`
+	Endpoint string `yaml:"endpoint,omitempty"`
+	Offline  *bool  `yaml:"offline,omitempty"`
+	DocsDir  string `yaml:"docs-dir,omitempty"`
+}
+
+// configKey describes one setting for `config get/set/list`.
+type configKey struct {
+	name string
+	env  string
+	help string
+	get  func(c *config) string
+	set  func(c *config, v string) error
+
+	// check, if set, is a stricter check of new values done by config set
+	// only, so that a value that later goes stale doesn't break startup.
+	check func(v string) error
+}
+
+// configKeys lists every setting in the order `config list` prints them.
+var configKeys = []configKey{
+	{
+		name: "version", env: "GH_ASK_DOCS_VERSION",
+		help: "docs version, e.g. enterprise-server@3.19",
+		get:  func(c *config) string { return c.Version },
+		set: func(c *config, v string) error {
+			// Only the plan is checked here: docsVersion warns about and
+			// falls back from releases that have left the supported list.
+			if v != "" && v != versionAuto {
+				if _, err := askdocs.ParseVersion(v); err != nil && !knownPlan(v) {
+					return err
+				}
+			}
+			c.Version = v
+			return nil
+		},
+		check: func(v string) error {
+			if v == versionAuto {
+				return nil
+			}
+			_, err := askdocs.ParseVersion(v)
+			return err
+		},
+	},
+	{
+		name: "language", env: "GH_ASK_DOCS_LANGUAGE",
+		help: "docs language, e.g. ja, or auto to follow LANG/LC_ALL",
+		get:  func(c *config) string { return c.Language },
+		set: func(c *config, v string) error {
+			if v != "" && v != languageAuto {
+				if _, err := askdocs.ParseLanguage(v); err != nil {
+					return err
+				}
+			}
+			c.Language = v
+			return nil
+		},
+	},
+	{
+		name: "theme", env: "GH_ASK_DOCS_THEME",
+		help: "color theme: auto, light, dark",
+		get:  func(c *config) string { return c.Theme },
+		set: func(c *config, v string) error {
+			switch v {
+			case "", "auto", "light", "dark":
+				c.Theme = v
+				return nil
+			}
+			return fmt.Errorf("invalid theme %q: use auto, light or dark", v)
+		},
+	},
+	{
+		name: "wrap", env: "GH_ASK_DOCS_WRAP",
+		help: "word-wrap width for rendered output (0 = no wrap)",
+		get: func(c *config) string {
+			if c.Wrap == nil {
+				return ""
+			}
+			return strconv.Itoa(*c.Wrap)
+		},
+		set: func(c *config, v string) error {
+			if v == "" {
+				c.Wrap = nil
+				return nil
+			}
+			w, err := strconv.Atoi(v)
+			if err != nil || w < 0 {
+				return fmt.Errorf("invalid wrap %q: must be a number >= 0", v)
+			}
+			c.Wrap = &w
+			return nil
+		},
+	},
+	{
+		name: "sources", env: "GH_ASK_DOCS_SOURCES",
+		help: "show reference links after answers: true, false",
+		get: func(c *config) string {
+			if c.Sources == nil {
+				return ""
+			}
+			return strconv.FormatBool(*c.Sources)
+		},
+		set: func(c *config, v string) error {
+			if v == "" {
+				c.Sources = nil
+				return nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid sources %q: use true or false", v)
+			}
+			c.Sources = &b
+			return nil
+		},
+	},
+	{
+		name: "format", env: "GH_ASK_DOCS_FORMAT",
+		help: "output format: text, json, ndjson",
+		get:  func(c *config) string { return c.Format },
+		set: func(c *config, v string) error {
+			switch v {
+			case "", formatText, formatJSON, formatNDJSON:
+				c.Format = v
+				return nil
+			}
+			return fmt.Errorf("invalid format %q: use text, json or ndjson", v)
+		},
+	},
+	{
+		name: "endpoint", env: "GH_ASK_DOCS_ENDPOINT",
+		help: "AI Search API endpoint",
+		get:  func(c *config) string { return c.Endpoint },
+		set: func(c *config, v string) error {
+			if v != "" {
+				if err := validateEndpoint(v); err != nil {
+					return err
+				}
+			}
+			c.Endpoint = v
+			return nil
+		},
+	},
+	{
+		name: "offline", env: "GH_ASK_DOCS_OFFLINE",
+		help: "answer from the local docs index instead of docs.github.com: true, false",
+		get: func(c *config) string {
+			if c.Offline == nil {
+				return ""
+			}
+			return strconv.FormatBool(*c.Offline)
+		},
+		set: func(c *config, v string) error {
+			if v == "" {
+				c.Offline = nil
+				return nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid offline %q: use true or false", v)
+			}
+			c.Offline = &b
+			return nil
+		},
+	},
+	{
+		name: "docs-dir", env: "GH_ASK_DOCS_DOCS_DIR",
+		help: "github/docs checkout the offline index is built from",
+		get:  func(c *config) string { return c.DocsDir },
+		set: func(c *config, v string) error {
+			c.DocsDir = v
+			return nil
+		},
+	},
+}
+
+// lookupConfigKey returns the setting called name.
+func lookupConfigKey(name string) (configKey, error) {
+	for _, k := range configKeys {
+		if k.name == name {
+			return k, nil
+		}
+	}
+	names := make([]string, len(configKeys))
+	for i, k := range configKeys {
+		names[i] = k.name
+	}
+	return configKey{}, fmt.Errorf("unknown config key %q (valid keys: %s)", name, strings.Join(names, ", "))
+}
+
+// merge returns c with every field that is set in over replacing its own.
+func (c config) merge(over config) config {
+	if over.Version != "" {
+		c.Version = over.Version
+	}
+	if over.Language != "" {
+		c.Language = over.Language
+	}
+	if over.Theme != "" {
+		c.Theme = over.Theme
+	}
+	if over.Wrap != nil {
+		c.Wrap = over.Wrap
+	}
+	if over.Sources != nil {
+		c.Sources = over.Sources
+	}
+	if over.Format != "" {
+		c.Format = over.Format
+	}
+	if over.Endpoint != "" {
+		c.Endpoint = over.Endpoint
+	}
+	if over.Offline != nil {
+		c.Offline = over.Offline
+	}
+	if over.DocsDir != "" {
+		c.DocsDir = over.DocsDir
+	}
+	return c
+}
+
+// apply copies the settings in c onto opts.
+func (c config) apply(opts *options) {
+	if c.Version != "" {
+		opts.version = c.Version
+	}
+	if c.Language != "" {
+		opts.language = c.Language
+	}
+	if c.Theme != "" {
+		opts.theme = c.Theme
+	}
+	if c.Wrap != nil {
+		opts.wrapWidth = *c.Wrap
+	}
+	if c.Sources != nil {
+		opts.showSources = *c.Sources
+	}
+	if c.Format != "" {
+		opts.format = c.Format
+	}
+	if c.Endpoint != "" {
+		opts.endpoint = c.Endpoint
+	}
+	if c.Offline != nil {
+		opts.offline = *c.Offline
+	}
+	if c.DocsDir != "" {
+		opts.docsDir = c.DocsDir
+	}
+}
+
+// userConfigPath returns ~/.config/gh-ask-docs/config.yml, honouring
+// XDG_CONFIG_HOME.
+func userConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "gh-ask-docs", "config.yml"), nil
+}
+
+// repoConfigPath looks for a .gh-ask-docs.yml in the working directory and its
+// parents, stopping at the repository root. It returns "" when there is none.
+func repoConfigPath() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return repoConfigPathFrom(dir)
+}
+
+// repoConfigPathFrom is repoConfigPath starting from dir.
+func repoConfigPathFrom(dir string) string {
+	for {
+		path := filepath.Join(dir, repoConfigName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readConfigFile parses and validates a config file. A missing file is an
+// empty config.
+func readConfigFile(path string) (config, error) {
+	c, err := loadConfigFile(path)
+	if err != nil {
+		return c, err
+	}
+	for _, k := range configKeys {
+		if err := k.set(&c, k.get(&c)); err != nil {
+			return c, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+// loadConfigFile reads the config file at path without validating its
+// values; a missing file is an empty config.
+func loadConfigFile(path string) (config, error) {
+	var c config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// writeConfigFile saves c to path, creating its directory if needed.
+func writeConfigFile(path string, c config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// envConfig reads the GH_ASK_DOCS_* environment variables.
+func envConfig() (config, error) {
+	var c config
+	for _, k := range configKeys {
+		if v := os.Getenv(k.env); v != "" {
+			if err := k.set(&c, v); err != nil {
+				return c, fmt.Errorf("%s: %w", k.env, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// fileConfig merges the user config with the repo config on top.
+func fileConfig() (config, error) {
+	return fileConfigFrom(repoConfigPath())
+}
+
+// fileConfigFrom merges the user config with the repo config at repoPath, if
+// any, on top.
+func fileConfigFrom(repoPath string) (config, error) {
+	var c config
+	if path, err := userConfigPath(); err == nil {
+		user, err := readConfigFile(path)
+		if err != nil {
+			return c, err
+		}
+		c = c.merge(user)
+	}
+	if repoPath != "" {
+		repo, err := readConfigFile(repoPath)
+		if err != nil {
+			return c, err
+		}
+		c = c.merge(repo)
+	}
+	return c, nil
+}
+
+// configuredOptions returns the defaults with the config files and the
+// environment applied, ready for flags to override:
+// flags > env > repo config > user config.
+func configuredOptions() (options, error) {
+	return configuredOptionsFrom(repoConfigPath())
+}
+
+// configuredOptionsFrom is configuredOptions with the repo config at repoPath.
+func configuredOptionsFrom(repoPath string) (options, error) {
+	opts := defaultOptions()
+
+	files, err := fileConfigFrom(repoPath)
+	if err != nil {
+		return opts, err
+	}
+	env, err := envConfig()
+	if err != nil {
+		return opts, err
+	}
+	files.merge(env).apply(&opts)
+	return opts, nil
+}
+
+// configGet prints the configured value of a setting.
+func configGet(name string) error {
+	k, err := lookupConfigKey(name)
+	if err != nil {
+		return err
+	}
+	c, err := fileConfig()
+	if err != nil {
+		return err
+	}
+	fmt.Println(k.get(&c))
+	return nil
+}
+
+// configSet saves a setting in the user config, or in the repository's
+// .gh-ask-docs.yml when repo is set.
+func configSet(name, value string, repo bool) error {
+	k, err := lookupConfigKey(name)
+	if err != nil {
+		return err
+	}
+
+	path := repoConfigPath()
+	if path == "" {
+		path = repoConfigName
+	}
+	if !repo {
+		if path, err = userConfigPath(); err != nil {
+			return err
+		}
+	}
+	// Only the key being set is validated, so that a bad value elsewhere in
+	// the file can still be fixed with config set.
+	c, err := loadConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if k.check != nil {
+		if err := k.check(value); err != nil {
+			return err
+		}
+	}
+	if err := k.set(&c, value); err != nil {
+		return err
+	}
+	return writeConfigFile(path, c)
+}
+
+// knownPlan reports whether version names a docs plan, whatever follows it.
+func knownPlan(version string) bool {
+	plan, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(version)), "@")
+	return plan == askdocs.PlanFreeProTeam || plan == askdocs.PlanEnterpriseCloud || plan == askdocs.PlanEnterpriseServer
+}
+
+// configList prints every setting with its configured value.
+func configList() error {
+	c, err := fileConfig()
+	if err != nil {
+		return err
+	}
+	for _, k := range configKeys {
+		fmt.Printf("%s=%s\n", k.name, k.get(&c))
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withConfigDirs isolates the user config, the working directory and the
+// GH_ASK_DOCS_* environment. It returns the user config path and the
+// repository root, which is also the working directory.
+func withConfigDirs(t *testing.T) (userPath, repoDir string) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, k := range configKeys {
+		t.Setenv(k.env, "")
+	}
+
+	repoDir = t.TempDir()
+	if err := os.Mkdir(filepath.Join(repoDir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(repoDir)
+
+	userPath, err := userConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userPath, repoDir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfiguredOptionsPrecedence(t *testing.T) {
+	userPath, repoDir := withConfigDirs(t)
+
+	opts, err := configuredOptions()
+	if err != nil {
+		t.Fatalf("configuredOptions() error: %v", err)
+	}
+	if opts.version != "free-pro-team" || opts.theme != "auto" || opts.showSources {
+		t.Errorf("without config got %+v, want the defaults", opts)
+	}
+
+	writeFile(t, userPath, "version: enterprise-server@3.19\ntheme: dark\nwrap: 80\nsources: true\n")
+	writeFile(t, filepath.Join(repoDir, repoConfigName), "version: enterprise-cloud@latest\nwrap: 100\n")
+	t.Setenv("GH_ASK_DOCS_WRAP", "120")
+
+	opts, err = configuredOptions()
+	if err != nil {
+		t.Fatalf("configuredOptions() error: %v", err)
+	}
+	if opts.theme != "dark" || !opts.showSources {
+		t.Errorf("user config not applied: theme=%q sources=%v", opts.theme, opts.showSources)
+	}
+	if opts.version != "enterprise-cloud@latest" {
+		t.Errorf("version = %q, repo config should override user config", opts.version)
+	}
+	if opts.wrapWidth != 120 {
+		t.Errorf("wrapWidth = %d, env should override config files", opts.wrapWidth)
+	}
+
+	opts, err = parseArgs(opts, []string{"--wrap", "40", "--version", "free-pro-team", "q"})
+	if err != nil {
+		t.Fatalf("parseArgs() error: %v", err)
+	}
+	if opts.wrapWidth != 40 || opts.version != "free-pro-team" {
+		t.Errorf("flags should override everything, got wrap=%d version=%q", opts.wrapWidth, opts.version)
+	}
+	if opts.theme != "dark" {
+		t.Errorf("theme = %q, unset flags should keep configured values", opts.theme)
+	}
+}
+
+func TestRepoConfigFromSubdirectory(t *testing.T) {
+	_, repoDir := withConfigDirs(t)
+	writeFile(t, filepath.Join(repoDir, repoConfigName), "format: json\n")
+
+	sub := filepath.Join(repoDir, "docs", "guides")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+
+	opts, err := configuredOptions()
+	if err != nil {
+		t.Fatalf("configuredOptions() error: %v", err)
+	}
+	if opts.format != formatJSON {
+		t.Errorf("format = %q, want %q from the repository root", opts.format, formatJSON)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"invalid yaml", "version: [", nil, "config.yml"},
+		{"invalid theme", "theme: blue\n", nil, "invalid theme"},
+		{"invalid language", "language: tlh\n", nil, "unsupported docs language"},
+		{"invalid version", "version: enterprise-sever@3.19\n", nil, "invalid docs version"},
+		{"invalid wrap", "wrap: -1\n", nil, "invalid wrap"},
+		{"invalid endpoint", "endpoint: docs.github.com\n", nil, "invalid endpoint"},
+		{"invalid env", "", map[string]string{"GH_ASK_DOCS_SOURCES": "maybe"}, "GH_ASK_DOCS_SOURCES"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userPath, _ := withConfigDirs(t)
+			writeFile(t, userPath, tt.user)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := configuredOptions()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("configuredOptions() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// runConfig runs `config` with args through the command tree.
+func runConfig(args ...string) error {
+	root := newRootCmd(defaultOptions(), func(options) error { return nil })
+	root.SetArgs(append([]string{"config"}, args...))
+	root.SetOut(io.Discard)
+	return root.Execute()
+}
+
+func TestConfigCommand(t *testing.T) {
+	userPath, repoDir := withConfigDirs(t)
+
+	if err := runConfig("set", "version", "enterprise-server@3.19"); err != nil {
+		t.Fatalf("config set error: %v", err)
+	}
+	if err := runConfig("set", "sources", "true"); err != nil {
+		t.Fatalf("config set error: %v", err)
+	}
+	if err := runConfig("set", "--repo", "wrap", "80"); err != nil {
+		t.Fatalf("config set --repo error: %v", err)
+	}
+
+	user, err := os.ReadFile(userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(user) != "version: enterprise-server@3.19\nsources: true\n" {
+		t.Errorf("user config = %q", user)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, repoConfigName)); err != nil {
+		t.Errorf("--repo should write %s: %v", repoConfigName, err)
+	}
+
+	got := captureStdout(t, func() {
+		if err := runConfig("get", "wrap"); err != nil {
+			t.Errorf("config get error: %v", err)
+		}
+	})
+	if got != "80\n" {
+		t.Errorf("config get wrap = %q, want %q", got, "80\n")
+	}
+
+	got = captureStdout(t, func() {
+		if err := runConfig("list"); err != nil {
+			t.Errorf("config list error: %v", err)
+		}
+	})
+	want := "version=enterprise-server@3.19\nlanguage=\ntheme=\nwrap=80\nsources=true\nformat=\nendpoint=\noffline=\ndocs-dir=\n"
+	if got != want {
+		t.Errorf("config list = %q, want %q", got, want)
+	}
+
+	for _, args := range [][]string{
+		{"get"},
+		{"get", "color"},
+		{"set", "theme", "blue"},
+		{"set", "version", "ghes@3.19"},
+		{"set", "version", "enterprise-server@3.12"},
+		{"set", "wrap"},
+		{"remove", "wrap"},
+	} {
+		if err := runConfig(args...); err == nil {
+			t.Errorf("runConfig(%q) should fail", args)
+		}
+	}
+}
+
+func TestConfigSetFixesInvalidFile(t *testing.T) {
+	userPath, _ := withConfigDirs(t)
+	writeFile(t, userPath, "theme: blue\nwrap: 80\n")
+
+	if err := runConfig("set", "version", "auto"); err != nil {
+		t.Fatalf("config set should not fail on another key's value: %v", err)
+	}
+	if err := runConfig("set", "theme", "dark"); err != nil {
+		t.Fatalf("config set should fix the invalid value: %v", err)
+	}
+	user, err := os.ReadFile(userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(user) != "version: auto\ntheme: dark\nwrap: 80\n" {
+		t.Errorf("user config = %q", user)
+	}
+}
+
+func TestConfigUnsupportedRelease(t *testing.T) {
+	userPath, _ := withConfigDirs(t)
+	writeFile(t, userPath, "version: enterprise-server@3.12\n")
+
+	// A release that has left the supported list is left to docsVersion to
+	// warn about, and --version can still override it.
+	opts, err := configuredOptions()
+	if err != nil || opts.version != "enterprise-server@3.12" {
+		t.Fatalf("configuredOptions() = %q, %v; want the stale release kept", opts.version, err)
+	}
+	opts, err = parseArgs(opts, []string{"--version", "free-pro-team", "q"})
+	if err != nil || opts.version != "free-pro-team" {
+		t.Errorf("--version = %q, %v; want it to override the config", opts.version, err)
+	}
+
+	t.Setenv("GH_ASK_DOCS_VERSION", "enterprise-server@3.15")
+	if opts, err := configuredOptions(); err != nil || opts.version != "enterprise-server@3.15" {
+		t.Errorf("configuredOptions() with GH_ASK_DOCS_VERSION = %q, %v", opts.version, err)
+	}
+}
+
+func TestRequireConfig(t *testing.T) {
+	withConfigDirs(t)
+	cfgErr := errors.New("config.yml: invalid theme \"blue\"")
+
+	for _, args := range [][]string{{"q"}, {"versions"}, {"batch", "questions.txt"}, {"explain-run", "1"}} {
+		root := newRootCmd(defaultOptions(), func(options) error { return nil })
+		requireConfig(root, cfgErr)
+		root.SetArgs(args)
+		root.SetOut(io.Discard)
+		if err := root.Execute(); !errors.Is(err, cfgErr) {
+			t.Errorf("%q error = %v, want the config error", args, err)
+		}
+	}
+
+	root := newRootCmd(defaultOptions(), func(options) error { return nil })
+	requireConfig(root, cfgErr)
+	root.SetArgs([]string{"config", "set", "theme", "dark"})
+	if err := root.Execute(); err != nil {
+		t.Errorf("config set error = %v, want it to run despite the config error", err)
+	}
+}
@@ -0,0 +1,10 @@
+// Package data holds the data files built into gh-ask-docs.
+package data
+
+import _ "embed"
+
+// SupportedVersions is supported-versions.json, kept current by the
+// update-enterprise-versions workflow.
+//
+//go:embed supported-versions.json
+var SupportedVersions []byte
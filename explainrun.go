@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// Limits on what explain-run puts in the question.
+const (
+	maxErrorLines    = 40 // error lines (with context) taken from the log
+	errorLineContext = 2  // lines kept before each error line
+	maxLogTailLines  = 30 // taken from the end of the log when no line looks like an error
+	maxStepYAMLBytes = 4 * 1024
+)
+
+// workflowRun is the part of a workflow run explain-run needs.
+type workflowRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Path       string `json:"path"` // e.g. .github/workflows/ci.yml
+	HeadSHA    string `json:"head_sha"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	HTMLURL    string `json:"html_url"`
+}
+
+// workflowJob is a job of a workflow run and its steps.
+type workflowJob struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Conclusion string `json:"conclusion"`
+	Steps      []struct {
+		Name       string `json:"name"`
+		Number     int    `json:"number"`
+		Conclusion string `json:"conclusion"`
+	} `json:"steps"`
+}
+
+// failedConclusions are the job and step conclusions explain-run looks into.
+var failedConclusions = []string{"failure", "timed_out", "startup_failure"}
+
+// runFailure is what explain-run found out about a failed run.
+type runFailure struct {
+	Run        workflowRun
+	Job        string
+	Step       string // "" when no step failed, e.g. the job timed out
+	StepYAML   string // the failed step's definition from the workflow file, if found
+	ErrorLines []string
+	OtherJobs  []string // other jobs that failed
+}
+
+// parseRunTarget accepts a run ID, with the repository from --repo or the
+// current directory, or the URL of a run or one of its jobs.
+func parseRunTarget(arg, repo string) (repoRef, int64, error) {
+	if u, err := url.Parse(arg); err == nil && u.Host != "" {
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) >= 5 && parts[2] == "actions" && parts[3] == "runs" {
+			if id, err := strconv.ParseInt(parts[4], 10, 64); err == nil && id > 0 {
+				return repoRef{Host: u.Host, Owner: parts[0], Name: parts[1]}, id, nil
+			}
+		}
+		return repoRef{}, 0, fmt.Errorf("invalid run %q: use a run ID or https://HOST/OWNER/REPO/actions/runs/ID", arg)
+	}
+
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		return repoRef{}, 0, fmt.Errorf("invalid run ID %q", arg)
+	}
+	var r repoRef
+	if repo != "" {
+		r, err = parseRepo(repo)
+	} else {
+		r, err = currentRepo()
+	}
+	return r, id, err
+}
+
+// fetchRunFailure looks up the run, picks the failed job (the one named
+// jobName, or else the first) and reads its log and the workflow file.
+func fetchRunFailure(ctx context.Context, client *githubClient, repo repoRef, runID int64, jobName string) (*runFailure, error) {
+	prefix := fmt.Sprintf("/repos/%s/%s/actions", repo.Owner, repo.Name)
+
+	var f runFailure
+	if err := client.do(ctx, "GET", fmt.Sprintf("%s/runs/%d", prefix, runID), nil, &f.Run); err != nil {
+		return nil, fmt.Errorf("getting run %d in %s: %w", runID, repo, err)
+	}
+	if f.Run.Status != "completed" {
+		return nil, fmt.Errorf("run %d is still %s", runID, strings.ReplaceAll(f.Run.Status, "_", " "))
+	}
+
+	var jobs struct {
+		Jobs []workflowJob `json:"jobs"`
+	}
+	if err := client.do(ctx, "GET", fmt.Sprintf("%s/runs/%d/jobs?filter=latest&per_page=100", prefix, runID), nil, &jobs); err != nil {
+		return nil, fmt.Errorf("listing the jobs of run %d: %w", runID, err)
+	}
+	var job *workflowJob
+	for i, j := range jobs.Jobs {
+		switch {
+		case !slices.Contains(failedConclusions, j.Conclusion):
+		case job == nil && (jobName == "" || strings.EqualFold(j.Name, jobName)):
+			job = &jobs.Jobs[i]
+		default:
+			f.OtherJobs = append(f.OtherJobs, j.Name)
+		}
+	}
+	if job == nil {
+		if jobName != "" {
+			return nil, fmt.Errorf("run %d has no failed job named %q", runID, jobName)
+		}
+		return nil, fmt.Errorf("run %d has no failed jobs (conclusion: %s)", runID, f.Run.Conclusion)
+	}
+	f.Job = job.Name
+	for _, s := range job.Steps {
+		if slices.Contains(failedConclusions, s.Conclusion) {
+			f.Step = s.Name
+			break
+		}
+	}
+
+	log, err := client.tail(ctx, fmt.Sprintf("%s/jobs/%d/logs", prefix, job.ID), maxJobLogSize)
+	if err != nil {
+		return nil, fmt.Errorf("downloading the log of job %q: %w", job.Name, err)
+	}
+	f.ErrorLines = extractErrorLines(string(log))
+
+	// The workflow file is context only; a run without one (e.g. a dynamic
+	// workflow) is still worth explaining.
+	if f.Step != "" && strings.HasPrefix(f.Run.Path, ".github/") {
+		path, _, _ := strings.Cut(f.Run.Path, "@")
+		var file struct {
+			Content  string `json:"content"`
+			Encoding string `json:"encoding"`
+		}
+		err := client.do(ctx, "GET", fmt.Sprintf("/repos/%s/%s/contents/%s?ref=%s", repo.Owner, repo.Name, path, f.Run.HeadSHA), nil, &file)
+		if err == nil && file.Encoding == "base64" {
+			if data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", "")); err == nil {
+				f.StepYAML = stepDefinition(data, f.Job, f.Step)
+			}
+		}
+	}
+	return &f, nil
+}
+
+var (
+	// logTimestampRe matches the timestamp Actions puts before each log line.
+	logTimestampRe = regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?Z ?`)
+
+	// errorLineRe matches log lines that look like an error.
+	errorLineRe = regexp.MustCompile(`(?i)##\[error\]|\berror\b|\bfatal\b|\bfailed\b|\bpanic:|exception\b|exit code [1-9]|^FAIL\b`)
+)
+
+// extractErrorLines returns the lines of a job log that look like errors,
+// each with the errorLineContext lines before it, and "..." between
+// separate excerpts. Only the last maxErrorLines are kept. When nothing looks
+// like an error, the last maxLogTailLines are returned.
+func extractErrorLines(log string) []string {
+	var lines []string
+	for _, l := range strings.Split(log, "\n") {
+		l = strings.TrimRight(askdocs.StripANSI(logTimestampRe.ReplaceAllString(l, "")), " \r")
+		if strings.HasPrefix(l, "##[group]") || strings.HasPrefix(l, "##[endgroup]") || strings.TrimSpace(l) == "" {
+			continue
+		}
+		lines = append(lines, strings.Replace(l, "##[error]", "Error: ", 1))
+	}
+
+	var out []string
+	next := 0 // first line not yet in out
+	for i, l := range lines {
+		if !errorLineRe.MatchString(l) {
+			continue
+		}
+		start := max(i-errorLineContext, next)
+		if start > next && len(out) > 0 {
+			out = append(out, "...")
+		}
+		out = append(out, lines[start:i+1]...)
+		next = i + 1
+	}
+	if len(out) == 0 {
+		out = lines[max(0, len(lines)-maxLogTailLines):]
+	}
+	if len(out) > maxErrorLines {
+		out = append([]string{"..."}, out[len(out)-maxErrorLines:]...)
+	}
+	return out
+}
+
+// stepDefinition returns the YAML of step in job from a workflow file, or ""
+// when it cannot be found. Jobs are matched by name or ID, ignoring a matrix
+// suffix like " (ubuntu-latest, 20)"; steps by name, or by the "Run ..."
+// name Actions gives unnamed steps.
+func stepDefinition(workflow []byte, job, step string) string {
+	var wf struct {
+		Jobs map[string]struct {
+			Name  string      `yaml:"name"`
+			Steps []yaml.Node `yaml:"steps"`
+		} `yaml:"jobs"`
+	}
+	if yaml.Unmarshal(workflow, &wf) != nil {
+		return ""
+	}
+
+	job, _, _ = strings.Cut(job, " (")
+	for id, j := range wf.Jobs {
+		name, _, _ := strings.Cut(j.Name, " (")
+		if id != job && name != job {
+			continue
+		}
+		for _, node := range j.Steps {
+			var s struct {
+				Name string `yaml:"name"`
+				Uses string `yaml:"uses"`
+				Run  string `yaml:"run"`
+			}
+			if node.Decode(&s) != nil {
+				continue
+			}
+			firstLine, _, _ := strings.Cut(strings.TrimSpace(s.Run), "\n")
+			if s.Name != step && "Run "+s.Uses != step && "Run "+firstLine != step {
+				continue
+			}
+			data, err := yaml.Marshal(&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{&node}})
+			if err != nil || len(data) > maxStepYAMLBytes {
+				return ""
+			}
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
+
+// query builds the question asked about the failure.
+func (f *runFailure) query() string {
+	var q strings.Builder
+	q.WriteString("My GitHub Actions workflow run failed. Why did it fail, and how do I fix it?\n\n")
+	fmt.Fprintf(&q, "Workflow: %s (%s)\n", f.Run.Name, f.Run.Path)
+	fmt.Fprintf(&q, "Failed job: %s\n", f.Job)
+	if f.Step != "" {
+		fmt.Fprintf(&q, "Failed step: %s\n", f.Step)
+	}
+	if f.StepYAML != "" {
+		q.WriteString("\nStep definition:\n\n")
+		q.WriteString(fenced(f.StepYAML, "yaml"))
+		q.WriteString("\n")
+	}
+	if len(f.ErrorLines) > 0 {
+		q.WriteString("\nLog excerpt:\n\n")
+		q.WriteString(fenced(strings.Join(f.ErrorLines, "\n"), ""))
+		q.WriteString("\n")
+	}
+	return strings.TrimSpace(q.String())
+}
+
+// runExplainRun explains why a workflow run failed: the question is built
+// from the failed job's log and step definition, then asked like any other.
+func runExplainRun(opts options, repo repoRef, runID int64, jobName string) error {
+	if err := resolveAutoVersion(&opts); err != nil {
+		return err
+	}
+	if err := resolveLanguage(&opts); err != nil {
+		return err
+	}
+
+	ctx, cancel := askContext(opts)
+	client, err := newGitHubClient(repo.Host)
+	if err != nil {
+		cancel()
+		return err
+	}
+	f, err := fetchRunFailure(ctx, client, repo, runID, jobName)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if opts.format == formatText {
+		fmt.Fprintf(os.Stderr, "Explaining %s", f.Job)
+		if f.Step != "" {
+			fmt.Fprintf(os.Stderr, " › %s", f.Step)
+		}
+		fmt.Fprintf(os.Stderr, " in %s\n", f.Run.HTMLURL)
+		if len(f.OtherJobs) > 0 {
+			fmt.Fprintf(os.Stderr, "Also failed: %s (pick one with --job)\n", strings.Join(f.OtherJobs, ", "))
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	if opts.debug {
+		fmt.Fprintf(os.Stderr, "%s\n\n", f.query())
+	}
+
+	opts.query = truncateQuestion(f.query())
+	return runAsk(opts)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const fakeWorkflow = `name: CI
+on: push
+jobs:
+  test:
+    name: Test (${{ matrix.node }})
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - name: Run tests
+        run: npm test
+`
+
+const fakeJobLog = "2024-05-01T10:00:00.0000000Z ##[group]Run npm test\n" +
+	"2024-05-01T10:00:00.1000000Z npm test\n" +
+	"2024-05-01T10:00:00.2000000Z ##[endgroup]\n" +
+	"2024-05-01T10:00:01.0000000Z > app@1.0.0 test\n" +
+	"2024-05-01T10:00:01.1000000Z > jest\n" +
+	"2024-05-01T10:00:02.0000000Z PASS src/a.test.js\n" +
+	"2024-05-01T10:00:03.0000000Z \x1b[31mFAIL\x1b[0m src/b.test.js\n" +
+	"2024-05-01T10:00:03.1000000Z   expected 2, received 3\n" +
+	"2024-05-01T10:00:04.0000000Z ##[error]Process completed with exit code 1.\n"
+
+// newFakeActions serves a failed run of octo/hello: its jobs, the failed
+// job's log and the workflow file.
+func newFakeActions(t *testing.T, conclusion string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		switch r.URL.Path {
+		case "/repos/octo/hello/actions/runs/42":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": 42, "name": "CI", "path": ".github/workflows/ci.yml", "head_sha": "abc123",
+				"status": "completed", "conclusion": conclusion,
+				"html_url": "https://github.com/octo/hello/actions/runs/42",
+			})
+		case "/repos/octo/hello/actions/runs/42/jobs":
+			if r.URL.Query().Get("filter") != "latest" {
+				t.Errorf("jobs query = %q", r.URL.RawQuery)
+			}
+			failed := conclusion
+			_, _ = w.Write([]byte(`{"jobs":[
+				{"id":1,"name":"Lint","conclusion":"success","steps":[]},
+				{"id":2,"name":"Test (18)","conclusion":"` + failed + `","steps":[
+					{"name":"Set up job","number":1,"conclusion":"success"},
+					{"name":"Run actions/checkout@v4","number":2,"conclusion":"success"},
+					{"name":"Run tests","number":3,"conclusion":"` + failed + `"}
+				]},
+				{"id":3,"name":"Test (20)","conclusion":"` + failed + `","steps":[]}
+			]}`))
+		case "/repos/octo/hello/actions/jobs/2/logs", "/repos/octo/hello/actions/jobs/3/logs":
+			// The real API redirects to a download URL.
+			http.Redirect(w, r, "/download/job-2.txt", http.StatusFound)
+		case "/download/job-2.txt":
+			_, _ = w.Write([]byte(fakeJobLog))
+		case "/repos/octo/hello/contents/.github/workflows/ci.yml":
+			if r.URL.Query().Get("ref") != "abc123" {
+				t.Errorf("contents ref = %q", r.URL.Query().Get("ref"))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"encoding": "base64",
+				"content":  base64.StdEncoding.EncodeToString([]byte(fakeWorkflow)),
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("GH_ASK_DOCS_GITHUB_API_URL", server.URL)
+	t.Setenv("GH_HOST", "")
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	t.Setenv("GH_TOKEN", "test-token")
+}
+
+func TestFetchRunFailure(t *testing.T) {
+	newFakeActions(t, "failure")
+	client, _ := newGitHubClient("github.com")
+	repo := repoRef{Host: "github.com", Owner: "octo", Name: "hello"}
+
+	f, err := fetchRunFailure(context.Background(), client, repo, 42, "")
+	if err != nil {
+		t.Fatalf("fetchRunFailure() error: %v", err)
+	}
+	if f.Job != "Test (18)" || f.Step != "Run tests" || !slices.Equal(f.OtherJobs, []string{"Test (20)"}) {
+		t.Errorf("failure = %+v", f)
+	}
+	if f.StepYAML != "- name: Run tests\n  run: npm test" {
+		t.Errorf("StepYAML = %q", f.StepYAML)
+	}
+	wantLines := []string{
+		"> jest",
+		"PASS src/a.test.js",
+		"FAIL src/b.test.js",
+		"  expected 2, received 3",
+		"Error: Process completed with exit code 1.",
+	}
+	if !slices.Equal(f.ErrorLines, wantLines) {
+		t.Errorf("ErrorLines = %q, want %q", f.ErrorLines, wantLines)
+	}
+
+	q := f.query()
+	for _, want := range []string{"Workflow: CI (.github/workflows/ci.yml)", "Failed step: Run tests", "```yaml\n- name: Run tests", "FAIL src/b.test.js"} {
+		if !strings.Contains(q, want) {
+			t.Errorf("query %q does not contain %q", q, want)
+		}
+	}
+
+	if _, err := fetchRunFailure(context.Background(), client, repo, 42, "Deploy"); err == nil || !strings.Contains(err.Error(), `no failed job named "Deploy"`) {
+		t.Errorf("fetchRunFailure() with an unknown --job error = %v", err)
+	}
+}
+
+func TestFetchRunFailureSucceededRun(t *testing.T) {
+	newFakeActions(t, "success")
+	client, _ := newGitHubClient("github.com")
+
+	_, err := fetchRunFailure(context.Background(), client, repoRef{Host: "github.com", Owner: "octo", Name: "hello"}, 42, "")
+	if err == nil || !strings.Contains(err.Error(), "no failed jobs") {
+		t.Errorf("fetchRunFailure() error = %v, want no failed jobs", err)
+	}
+}
+
+func TestRunExplainRun(t *testing.T) {
+	withTempCacheDir(t)
+	newFakeActions(t, "failure")
+	var asked string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		asked = payload["query"]
+		_, _ = w.Write([]byte(`{"chunkType":"SOURCES","sources":[{"title":"Troubleshooting","url":"https://docs.github.com/en/actions/troubleshooting"}]}` + "\n"))
+		_, _ = w.Write([]byte(`{"chunkType":"MESSAGE_CHUNK","text":"A test failed."}` + "\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	opts := defaultOptions()
+	opts.endpoint = server.URL
+	opts.format = formatJSON
+	opts.language = "en"
+	var out string
+	captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			if err := runExplainRun(opts, repoRef{Host: "github.com", Owner: "octo", Name: "hello"}, 42, "Test (20)"); err != nil {
+				t.Errorf("runExplainRun() error: %v", err)
+			}
+		})
+	})
+
+	if !strings.Contains(asked, "Failed job: Test (20)") {
+		t.Errorf("asked %q, want the job picked with --job", asked)
+	}
+	if !strings.Contains(out, `"answer": "A test failed."`) || !strings.Contains(out, "actions/troubleshooting") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestExtractErrorLines(t *testing.T) {
+	var log strings.Builder
+	for i := range 100 {
+		log.WriteString("line " + string(rune('a'+i%26)) + "\n")
+	}
+	if got := extractErrorLines(log.String()); len(got) != maxLogTailLines {
+		t.Errorf("without errors got %d lines, want the last %d", len(got), maxLogTailLines)
+	}
+
+	got := extractErrorLines("one\ntwo\nthree\nfatal: not a git repository\nfour\nfive\nsix\nseven\nnpm ERR! failed\n")
+	want := []string{"two", "three", "fatal: not a git repository", "...", "six", "seven", "npm ERR! failed"}
+	if !slices.Equal(got, want) {
+		t.Errorf("extractErrorLines() = %q, want %q", got, want)
+	}
+
+	log.Reset()
+	for range maxErrorLines {
+		log.WriteString("error: again\n")
+	}
+	log.WriteString("error: last\n")
+	if got := extractErrorLines(log.String()); len(got) != maxErrorLines+1 || got[0] != "..." || got[len(got)-1] != "error: last" {
+		t.Errorf("extractErrorLines() kept %d lines, want the last %d after ...", len(got), maxErrorLines)
+	}
+}
+
+func TestGitHubClientTail(t *testing.T) {
+	var log strings.Builder
+	for i := range 10000 {
+		fmt.Fprintf(&log, "line %d\n", i)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(log.String()))
+	}))
+	t.Cleanup(server.Close)
+	c := &githubClient{BaseURL: server.URL}
+
+	got, err := c.tail(context.Background(), "/logs", 100)
+	if err != nil {
+		t.Fatalf("tail() error: %v", err)
+	}
+	if len(got) > 100 || !strings.HasPrefix(string(got), "line ") || !strings.HasSuffix(string(got), "line 9999\n") {
+		t.Errorf("tail() = %q, want whole lines from the end of the log", got)
+	}
+
+	got, err = c.tail(context.Background(), "/logs", 1<<20)
+	if err != nil || string(got) != log.String() {
+		t.Errorf("tail() of a short log returned %d bytes, %v; want all of it", len(got), err)
+	}
+}
+
+func TestStepDefinition(t *testing.T) {
+	tests := []struct {
+		job, step, want string
+	}{
+		{"Test (18)", "Run tests", "- name: Run tests\n  run: npm test"},
+		{"test", "Run actions/checkout@v4", "- uses: actions/checkout@v4"},
+		{"Test (18)", "Deploy", ""},
+		{"Build", "Run tests", ""},
+	}
+	for _, tt := range tests {
+		if got := stepDefinition([]byte(fakeWorkflow), tt.job, tt.step); got != tt.want {
+			t.Errorf("stepDefinition(%q, %q) = %q, want %q", tt.job, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestParseRunTarget(t *testing.T) {
+	t.Setenv("GH_HOST", "")
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	t.Setenv("GH_REPO", "")
+	orig := gitOriginURL
+	t.Cleanup(func() { gitOriginURL = orig })
+	gitOriginURL = func() (string, error) { return "git@github.com:octo/hello.git", nil }
+
+	tests := []struct {
+		arg, repo string
+		want      repoRef
+	}{
+		{"42", "", repoRef{"github.com", "octo", "hello"}},
+		{"42", "other/repo", repoRef{"github.com", "other", "repo"}},
+		{"42", "ghes.example.com/other/repo", repoRef{"ghes.example.com", "other", "repo"}},
+		{"https://ghes.example.com/octo/app/actions/runs/42/job/7", "", repoRef{"ghes.example.com", "octo", "app"}},
+	}
+	for _, tt := range tests {
+		got, id, err := parseRunTarget(tt.arg, tt.repo)
+		if err != nil || got != tt.want || id != 42 {
+			t.Errorf("parseRunTarget(%q, %q) = %+v, %d, %v; want %+v", tt.arg, tt.repo, got, id, err, tt.want)
+		}
+	}
+
+	for _, arg := range []string{"0", "abc", "https://github.com/octo/hello/pull/1"} {
+		if _, _, err := parseRunTarget(arg, ""); err == nil {
+			t.Errorf("parseRunTarget(%q) should fail", arg)
+		}
+	}
+}
+
+func TestParseRemoteURL(t *testing.T) {
+	for _, remote := range []string{
+		"https://github.com/octo/hello.git",
+		"https://github.com/octo/hello",
+		"git@github.com:octo/hello.git",
+		"ssh://git@github.com/octo/hello.git",
+	} {
+		got, err := parseRemoteURL(remote)
+		if err != nil || got != (repoRef{"github.com", "octo", "hello"}) {
+			t.Errorf("parseRemoteURL(%q) = %+v, %v", remote, got, err)
+		}
+	}
+	if _, err := parseRemoteURL("/srv/git/hello"); err == nil {
+		t.Error("parseRemoteURL() of a local path should fail")
+	}
+}
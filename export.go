@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// Export formats accepted by --export-format.
+const (
+	exportMarkdown = "markdown"
+	exportHTML     = "html"
+	exportJSON     = "json"
+)
+
+// exportDoc is an answer as written to a file, independent of the terminal.
+type exportDoc struct {
+	Query          string           `json:"query" yaml:"query"`
+	Version        string           `json:"version" yaml:"version"`
+	Date           time.Time        `json:"date" yaml:"date"`
+	ConversationID string           `json:"conversation_id,omitempty" yaml:"conversation_id,omitempty"`
+	Answer         string           `json:"answer" yaml:"-"`
+	Sources        []askdocs.Source `json:"sources" yaml:"-"`
+}
+
+func exportFromResult(res *askdocs.Result) exportDoc {
+	date := res.Timings.StartedAt
+	if date.IsZero() || res.Cached {
+		date = time.Now()
+	}
+	return exportDoc{
+		Query:          res.Query,
+		Version:        res.Version,
+		Date:           date.UTC().Truncate(time.Second),
+		ConversationID: res.ConversationID,
+		Answer:         askdocs.StripANSI(strings.TrimSpace(res.Answer)),
+		Sources:        res.Sources,
+	}
+}
+
+func exportFromHistory(e *historyEntry) exportDoc {
+	return exportDoc{
+		Query:          e.Query,
+		Version:        e.Version,
+		Date:           e.AskedAt.UTC().Truncate(time.Second),
+		ConversationID: e.ConversationID,
+		Answer:         askdocs.StripANSI(strings.TrimSpace(e.Answer)),
+		Sources:        e.Sources,
+	}
+}
+
+// exportFormatFor picks the export format: explicit, else from the file
+// extension, else Markdown.
+func exportFormatFor(format, path string) (string, error) {
+	switch format {
+	case exportMarkdown, exportHTML, exportJSON:
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("invalid export format %q: use markdown, html or json", format)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return exportHTML, nil
+	case ".json":
+		return exportJSON, nil
+	}
+	return exportMarkdown, nil
+}
+
+// markdownBody is the question heading, answer and sources.
+func (d exportDoc) markdownBody() string {
+	var md strings.Builder
+	fmt.Fprintf(&md, "# %s\n\n", strings.Join(strings.Fields(d.Query), " "))
+	md.WriteString(d.Answer)
+	md.WriteString("\n")
+	if len(d.Sources) > 0 {
+		md.WriteString("\n## Sources\n\n")
+		for _, s := range d.Sources {
+			text := s.Title
+			if text == "" {
+				text = s.URL
+			}
+			fmt.Fprintf(&md, "- %s\n", askdocs.AutoLink(s.URL, text))
+		}
+	}
+	return md.String()
+}
+
+// writeExport writes d to w in the given format.
+func writeExport(w io.Writer, d exportDoc, format string) error {
+	switch format {
+	case exportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+
+	case exportHTML:
+		var body bytes.Buffer
+		md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+		if err := md.Convert([]byte(d.markdownBody()), &body); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, htmlTemplate,
+			html.EscapeString(d.Version),
+			html.EscapeString(d.Date.Format(time.RFC3339)),
+			html.EscapeString(d.Query),
+			body.String(),
+			html.EscapeString(d.Version),
+			html.EscapeString(d.Date.Format("January 2, 2006")),
+		)
+		return err
+	}
+
+	front, err := yaml.Marshal(d)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "---\n%s---\n\n%s", front, d.markdownBody())
+	return err
+}
+
+// exportToFile writes d to path ("-" for stdout).
+func exportToFile(d exportDoc, path, format string) error {
+	format, err := exportFormatFor(format, path)
+	if err != nil {
+		return err
+	}
+	if path == "" || path == "-" {
+		return writeExport(os.Stdout, d, format)
+	}
+
+	var buf bytes.Buffer
+	if err := writeExport(&buf, d, format); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="docs-version" content="%s">
+<meta name="date" content="%s">
+<title>%s</title>
+<style>
+body { max-width: 48rem; margin: 2rem auto; padding: 0 1rem; font: 16px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; }
+pre, code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 85%%; background: #f6f8fa; border-radius: 6px; }
+pre { padding: 1rem; overflow: auto; }
+code { padding: .2em .4em; }
+pre code { padding: 0; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d1d9e0; padding: .4em .8em; }
+a { color: #0969da; }
+footer { margin-top: 2rem; color: #59636e; font-size: 85%%; }
+</style>
+</head>
+<body>
+<main>
+%s</main>
+<footer>Answered by docs.github.com for %s on %s.</footer>
+</body>
+</html>
+`
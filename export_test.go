@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+func testExportDoc() exportDoc {
+	return exportDoc{
+		Query:          "How do I [fork] a repo?",
+		Version:        "enterprise-server@3.19",
+		Date:           time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		ConversationID: "conv-1",
+		Answer:         "Click **Fork**.\n\n```sh\ngh repo fork\n```\n\n<script>alert(1)</script>",
+		Sources: []askdocs.Source{
+			{Title: "Fork a repo", URL: "https://docs.github.com/fork"},
+			{URL: "https://docs.github.com/pulls"},
+		},
+	}
+}
+
+func TestExportFormatFor(t *testing.T) {
+	tests := []struct {
+		format, path, want string
+	}{
+		{"", "answer.md", exportMarkdown},
+		{"", "answer.HTML", exportHTML},
+		{"", "answer.htm", exportHTML},
+		{"", "answer.json", exportJSON},
+		{"", "answer", exportMarkdown},
+		{"", "-", exportMarkdown},
+		{exportJSON, "answer.md", exportJSON},
+	}
+	for _, tt := range tests {
+		if got, err := exportFormatFor(tt.format, tt.path); err != nil || got != tt.want {
+			t.Errorf("exportFormatFor(%q, %q) = %q, %v; want %q", tt.format, tt.path, got, err, tt.want)
+		}
+	}
+	if _, err := exportFormatFor("pdf", "answer.pdf"); err == nil {
+		t.Error("exportFormatFor(pdf) should fail")
+	}
+}
+
+func TestWriteExportMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeExport(&buf, testExportDoc(), exportMarkdown); err != nil {
+		t.Fatalf("writeExport() error: %v", err)
+	}
+	got := buf.String()
+
+	want := "---\n" +
+		"query: How do I [fork] a repo?\n" +
+		"version: enterprise-server@3.19\n" +
+		"date: 2026-10-16T12:00:00Z\n" +
+		"conversation_id: conv-1\n" +
+		"---\n\n" +
+		"# How do I [fork] a repo?\n\n"
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("Markdown export starts with\n%s\nwant\n%s", got, want)
+	}
+	for _, s := range []string{
+		"Click **Fork**.",
+		"## Sources\n\n- [Fork a repo](https://docs.github.com/fork)\n- <https://docs.github.com/pulls>\n",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("Markdown export missing %q:\n%s", s, got)
+		}
+	}
+	if strings.Contains(got, "\x1b[") {
+		t.Error("Markdown export contains ANSI escapes")
+	}
+}
+
+func TestWriteExportHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeExport(&buf, testExportDoc(), exportHTML); err != nil {
+		t.Fatalf("writeExport() error: %v", err)
+	}
+	got := buf.String()
+
+	for _, s := range []string{
+		"<!DOCTYPE html>",
+		"<title>How do I [fork] a repo?</title>",
+		`<meta name="docs-version" content="enterprise-server@3.19">`,
+		"<strong>Fork</strong>",
+		"<code class=\"language-sh\">gh repo fork",
+		`<a href="https://docs.github.com/fork">Fork a repo</a>`,
+		"<style>",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("HTML export missing %q:\n%s", s, got)
+		}
+	}
+	if strings.Contains(got, "<script>") {
+		t.Error("HTML export should not pass raw HTML from the answer through")
+	}
+	if strings.Contains(got, "%!") {
+		t.Errorf("HTML template has a formatting error:\n%s", got)
+	}
+}
+
+func TestWriteExportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeExport(&buf, testExportDoc(), exportJSON); err != nil {
+		t.Fatalf("writeExport() error: %v", err)
+	}
+	var got exportDoc
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := testExportDoc()
+	if got.Query != want.Query || got.Answer != want.Answer || len(got.Sources) != 2 || !got.Date.Equal(want.Date) {
+		t.Errorf("JSON export = %+v", got)
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	withTempCacheDir(t)
+	recordTestHistory(t, &askdocs.Result{
+		Query:   "Fork a repo",
+		Version: "free-pro-team@latest",
+		Answer:  "Click **Fork**.",
+	})
+
+	dir := t.TempDir()
+	for _, name := range []string{"answer.md", "answer.html", "answer.json"} {
+		path := filepath.Join(dir, name)
+		root := newRootCmd(defaultOptions(), func(options) error { return nil })
+		root.SetArgs([]string{"export", "1", "-o", path})
+		root.SetOut(io.Discard)
+		if err := root.Execute(); err != nil {
+			t.Fatalf("export -o %s error: %v", name, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || !strings.Contains(string(data), "Fork") {
+			t.Errorf("%s = %q, %v", name, data, err)
+		}
+	}
+
+	out := captureStdout(t, func() {
+		root := newRootCmd(defaultOptions(), func(options) error { return nil })
+		root.SetArgs([]string{"export", "#1", "--export-format", "json"})
+		if err := root.Execute(); err != nil {
+			t.Errorf("export to stdout error: %v", err)
+		}
+	})
+	if !strings.Contains(out, `"query": "Fork a repo"`) {
+		t.Errorf("export to stdout = %q", out)
+	}
+}
+
+func TestRunAskOutput(t *testing.T) {
+	withTempCacheDir(t)
+	server, _ := newSearchServer(t, forkAnswer, nil)
+	path := filepath.Join(t.TempDir(), "answer.md")
+
+	opts := defaultOptions()
+	opts.query = "How do I fork?"
+	opts.format = formatJSON
+	opts.theme = "dark"
+	opts.endpoint = server.URL
+	opts.output = path
+	runAskJSON(t, opts)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"query: How do I fork?", "# How do I fork?", "Use forks.", "[Forks](https://docs.github.com/forks)"} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("--output file missing %q:\n%s", s, data)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// defaultGitHubHost is used when GH_HOST is not set.
+const defaultGitHubHost = "github.com"
+
+// githubClient is a minimal GitHub REST API client.
+type githubClient struct {
+	BaseURL    string // e.g. https://api.github.com or https://ghes.example.com/api/v3
+	Token      string
+	HTTPClient *http.Client
+}
+
+// githubHost returns the host gh is working against: GH_HOST, else
+// github.com when gh is logged in there (or nowhere), else the first host in
+// gh's hosts.yml.
+func githubHost() string {
+	if h := os.Getenv("GH_HOST"); h != "" {
+		return h
+	}
+	hosts := ghHosts()
+	if len(hosts) == 0 || slices.Contains(hosts, defaultGitHubHost) {
+		return defaultGitHubHost
+	}
+	return hosts[0]
+}
+
+// ghHosts lists the hosts in gh's hosts.yml, in file order.
+func ghHosts() []string {
+	dir := os.Getenv("GH_CONFIG_DIR")
+	if dir == "" {
+		base := os.Getenv("XDG_CONFIG_HOME")
+		if base == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil
+			}
+			base = filepath.Join(home, ".config")
+		}
+		dir = filepath.Join(base, "gh")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yml"))
+	if err != nil {
+		return nil
+	}
+
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	var hosts []string
+	for i := 0; i < len(doc.Content[0].Content); i += 2 {
+		hosts = append(hosts, doc.Content[0].Content[i].Value)
+	}
+	return hosts
+}
+
+// githubAPIURL returns the REST API root for host. GH_ASK_DOCS_GITHUB_API_URL
+// overrides it, e.g. to point at a local fake server.
+func githubAPIURL(host string) string {
+	if u := os.Getenv("GH_ASK_DOCS_GITHUB_API_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	if host == defaultGitHubHost {
+		return "https://api.github.com"
+	}
+	if isTenancyHost(host) {
+		return "https://api." + host
+	}
+	return "https://" + host + "/api/v3"
+}
+
+// isTenancyHost reports whether host is a GitHub Enterprise Cloud with data
+// residency (*.ghe.com) host, which gh treats like github.com.
+func isTenancyHost(host string) bool {
+	return strings.HasSuffix(strings.ToLower(host), ".ghe.com")
+}
+
+// ghAuthToken asks gh for its token; swapped out in tests.
+var ghAuthToken = func(host string) (string, error) {
+	out, err := exec.Command("gh", "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		return "", fmt.Errorf("no GitHub token for %s: run `gh auth login` or set GH_TOKEN", host)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// githubToken returns the token gh would use for host: GH_TOKEN /
+// GITHUB_TOKEN for github.com and *.ghe.com (GH_ENTERPRISE_TOKEN /
+// GITHUB_ENTERPRISE_TOKEN for Enterprise Server hosts), then `gh auth token`.
+func githubToken(host string) (string, error) {
+	envs := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if host != defaultGitHubHost && !isTenancyHost(host) {
+		envs = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, env := range envs {
+		if v := os.Getenv(env); v != "" {
+			return v, nil
+		}
+	}
+	return ghAuthToken(host)
+}
+
+// newGitHubClient returns a client for host authenticated like gh.
+func newGitHubClient(host string) (*githubClient, error) {
+	token, err := githubToken(host)
+	if err != nil {
+		return nil, err
+	}
+	return &githubClient{
+		BaseURL:    githubAPIURL(host),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// do sends a request to path and decodes a JSON response into out, if set.
+// Non-2xx responses are returned as *askdocs.HTTPStatusError carrying the
+// API's message.
+func (c *githubClient) do(ctx context.Context, method, path string, in, out any) error {
+	data, err := c.send(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Response sizes read by send and tail. Job logs can be far larger than any
+// API response, and only their end explains a failure.
+const (
+	maxAPIResponseSize = 32 << 20
+	maxJobLogSize      = 4 << 20
+)
+
+// send sends a request to path and returns the response body, which must
+// not exceed maxAPIResponseSize.
+func (c *githubClient) send(ctx context.Context, method, path string, in any) ([]byte, error) {
+	resp, err := c.request(ctx, method, path, in)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAPIResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxAPIResponseSize {
+		return nil, fmt.Errorf("response from %s is larger than %d bytes", path, maxAPIResponseSize)
+	}
+	return data, nil
+}
+
+// tail GETs path and returns the last n bytes of the response body, from
+// the first whole line, following redirects such as the one to a job's logs.
+func (c *githubClient) tail(ctx context.Context, path string, n int) ([]byte, error) {
+	resp, err := c.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var buf []byte
+	truncated := false
+	chunk := make([]byte, 32*1024)
+	for {
+		m, err := resp.Body.Read(chunk)
+		buf = append(buf, chunk[:m]...)
+		if len(buf) > 2*n {
+			buf = append(buf[:0], buf[len(buf)-n:]...)
+			truncated = true
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(buf) > n {
+		buf = buf[len(buf)-n:]
+		truncated = true
+	}
+	if truncated {
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			buf = buf[i+1:]
+		}
+	}
+	return buf, nil
+}
+
+// request sends a request to path and returns the response for the caller
+// to read and close. Non-2xx responses are returned as
+// *askdocs.HTTPStatusError carrying the API's message.
+func (c *githubClient) request(ctx context.Context, method, path string, in any) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", askdocs.ErrRequestFailed, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			msg = apiErr.Message
+		}
+		return nil, &askdocs.HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: msg}
+	}
+	return resp, nil
+}
+
+// repoRef is a repository on a GitHub host.
+type repoRef struct {
+	Host  string
+	Owner string
+	Name  string
+}
+
+func (r repoRef) String() string {
+	return r.Owner + "/" + r.Name
+}
+
+// parseRepo accepts owner/repo, HOST/owner/repo or a repository URL. owner/repo
+// is on githubHost().
+func parseRepo(s string) (repoRef, error) {
+	rest := s
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		rest = u.Host + u.Path
+	}
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(rest, ".git"), "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return repoRef{Host: githubHost(), Owner: parts[0], Name: parts[1]}, nil
+	case len(parts) >= 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+		return repoRef{Host: parts[0], Owner: parts[1], Name: parts[2]}, nil
+	}
+	return repoRef{}, fmt.Errorf("invalid repository %q: use owner/repo", s)
+}
+
+// parseRemoteURL reads the repository from a git remote URL: https://,
+// ssh:// or git@host:owner/repo.
+func parseRemoteURL(remote string) (repoRef, error) {
+	if !strings.Contains(remote, "://") {
+		if userHost, path, ok := strings.Cut(remote, ":"); ok {
+			_, host, _ := strings.Cut(userHost, "@")
+			remote = "ssh://" + cmp.Or(host, userHost) + "/" + path
+		}
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.Host == "" {
+		return repoRef{}, fmt.Errorf("cannot tell the repository from remote %q", remote)
+	}
+	r, err := parseRepo(u.Hostname() + u.Path)
+	if err != nil {
+		return repoRef{}, fmt.Errorf("cannot tell the repository from remote %q", remote)
+	}
+	return r, nil
+}
+
+// gitOriginURL returns the URL of the origin remote; swapped out in tests.
+var gitOriginURL = func() (string, error) {
+	out, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", errors.New("not in a git repository with an origin remote: use --repo owner/repo")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// currentRepo returns the repository gh would use: GH_REPO, else the origin
+// remote of the git repository in the working directory.
+func currentRepo() (repoRef, error) {
+	if r := os.Getenv("GH_REPO"); r != "" {
+		return parseRepo(r)
+	}
+	remote, err := gitOriginURL()
+	if err != nil {
+		return repoRef{}, err
+	}
+	return parseRemoteURL(remote)
+}
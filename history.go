@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// historyEntry is one recorded question and its answer.
+type historyEntry struct {
+	ID             int              `json:"id"`
+	AskedAt        time.Time        `json:"asked_at"`
+	Query          string           `json:"query"`
+	Version        string           `json:"version"`
+	Language       string           `json:"language,omitempty"`
+	ConversationID string           `json:"conversation_id,omitempty"`
+	Answer         string           `json:"answer"`
+	Sources        []askdocs.Source `json:"sources"`
+	Cached         bool             `json:"cached,omitempty"`
+	Partial        bool             `json:"partial,omitempty"`
+	Error          string           `json:"error,omitempty"`
+}
+
+// historyPath returns ~/.local/state/gh-ask-docs/history.jsonl, honouring
+// XDG_STATE_HOME. History outlives the cache, so it is not kept there.
+func historyPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "gh-ask-docs", "history.jsonl"), nil
+}
+
+// loadHistory returns every recorded entry, oldest first. Lines that cannot
+// be parsed are skipped.
+func loadHistory() ([]historyEntry, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil && e.ID > 0 {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// historyLimit is how many entries the history keeps. Older entries are
+// pruned every tenth of historyLimit questions, so IDs stay stable between
+// prunes and never repeat.
+var historyLimit = 1000
+
+// historyLockTimeout is how long recordHistory waits for another process
+// to finish writing, and historyLockStale how old a lock file must be to be
+// considered left behind by a crashed process.
+const (
+	historyLockTimeout = 5 * time.Second
+	historyLockStale   = 30 * time.Second
+)
+
+// recordHistory appends the result of a question to the history and returns
+// the new entry's ID. A failed question is recorded with its error.
+func recordHistory(res *askdocs.Result, askErr error) (int, error) {
+	path, err := historyPath()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return 0, err
+	}
+	unlock, err := lockHistory(path)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	lastID, err := lastHistoryID(f)
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+
+	e := historyEntry{
+		ID:             lastID + 1,
+		AskedAt:        res.Timings.StartedAt,
+		Query:          res.Query,
+		Version:        res.Version,
+		Language:       res.Language,
+		ConversationID: res.ConversationID,
+		Answer:         res.Answer,
+		Sources:        res.Sources,
+		Cached:         res.Cached,
+		Partial:        res.Partial,
+	}
+	if e.AskedAt.IsZero() || e.Cached {
+		e.AskedAt = time.Now()
+	}
+	if askErr != nil {
+		e.Error = askErr.Error()
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if e.ID%max(historyLimit/10, 1) == 0 {
+		if err := pruneHistory(path); err != nil {
+			return e.ID, err
+		}
+	}
+	return e.ID, nil
+}
+
+// lockHistory creates path.lock, waiting for another process holding it,
+// and returns a function that removes it.
+func lockHistory(path string) (func(), error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(historyLockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > historyLockStale {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("history is locked by another gh ask-docs; remove %s if none is running", lock)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// lastHistoryID returns the ID of the last valid entry in f, reading the
+// file backwards so that only the last line is read in the common case.
+func lastHistoryID(f *os.File) (int, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	const chunk = 64 * 1024
+	end := info.Size()
+	var tail []byte
+	for end > 0 {
+		n := min(chunk, end)
+		end -= n
+		buf := make([]byte, n, n+int64(len(tail)))
+		if _, err := f.ReadAt(buf, end); err != nil {
+			return 0, err
+		}
+		tail = append(buf, tail...)
+
+		// The first line is only known to be whole at the start of the file.
+		lines := bytes.Split(tail, []byte("\n"))
+		first := 1
+		if end == 0 {
+			first = 0
+		}
+		for i := len(lines) - 1; i >= first; i-- {
+			var e struct {
+				ID int `json:"id"`
+			}
+			if json.Unmarshal(lines[i], &e) == nil && e.ID > 0 {
+				return e.ID, nil
+			}
+		}
+		tail = lines[0]
+	}
+	return 0, nil
+}
+
+// pruneHistory rewrites the history with only its last historyLimit
+// entries. The caller holds the history lock.
+func pruneHistory(path string) error {
+	entries, err := loadHistory()
+	if err != nil || len(entries) <= historyLimit {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries[len(entries)-historyLimit:] {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// parseHistoryID accepts an entry ID with or without a leading "#".
+func parseHistoryID(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(s, "#"))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid history ID %q", s)
+	}
+	return id, nil
+}
+
+// findHistory returns the entry with the given ID.
+func findHistory(id int) (*historyEntry, error) {
+	entries, err := loadHistory()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no history entry #%d", id)
+}
+
+// applyRerun sets up opts to ask the question from history entry opts.rerun
+// again, bypassing the answer cache. The recorded version and language are
+// used unless --version or --language was given.
+func applyRerun(opts *options, versionSet, languageSet bool) error {
+	if opts.query != "" {
+		return errors.New("--rerun cannot be combined with a question")
+	}
+	e, err := findHistory(opts.rerun)
+	if err != nil {
+		return err
+	}
+	opts.query = e.Query
+	if !versionSet {
+		opts.version = e.Version
+	}
+	if !languageSet && e.Language != "" {
+		opts.language = e.Language
+	}
+	opts.refresh = true
+	return nil
+}
+
+// addToHistory records a question unless --no-history is set.
+func addToHistory(res *askdocs.Result, askErr error, opts options) {
+	if opts.noHistory {
+		return
+	}
+	if _, err := recordHistory(res, askErr); err != nil && opts.debug {
+		fmt.Fprintf(os.Stderr, "could not record history: %v\n", err)
+	}
+}
+
+// searchHistory returns the entries whose question or answer contains every
+// term, ignoring case, newest first.
+func searchHistory(entries []historyEntry, terms []string) []historyEntry {
+	var matches []historyEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		text := strings.ToLower(entries[i].Query + "\n" + entries[i].Answer)
+		found := true
+		for _, term := range terms {
+			if !strings.Contains(text, strings.ToLower(term)) {
+				found = false
+				break
+			}
+		}
+		if found {
+			matches = append(matches, entries[i])
+		}
+	}
+	return matches
+}
+
+// clearHistory deletes the history file.
+func clearHistory() error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// printHistoryList writes one line per entry.
+func printHistoryList(entries []historyEntry) {
+	for _, e := range entries {
+		query := strings.Join(strings.Fields(e.Query), " ")
+		if len([]rune(query)) > 60 {
+			query = string([]rune(query)[:59]) + "…"
+		}
+		state := ""
+		switch {
+		case e.Error != "" && !e.Partial:
+			state = " (failed)"
+		case e.Partial:
+			state = " (partial)"
+		}
+		fmt.Printf("%4d  %s  %-28s %s%s\n", e.ID, e.AskedAt.Local().Format("2006-01-02 15:04"), e.Version, query, state)
+	}
+}
+
+// showHistory re-renders a recorded answer.
+func showHistory(e *historyEntry, opts options) error {
+	r, err := newRenderers(opts.theme, opts.wrapWidth)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "#%d · %s · %s\n", e.ID, e.Version, e.AskedAt.Local().Format(time.RFC1123))
+	if e.Error != "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", e.Error)
+	}
+
+	md := "## " + e.Query + "\n\n" + e.Answer
+	if opts.raw {
+		fmt.Println(md)
+	} else {
+		out, _ := r.answer.Render(md)
+		fmt.Print(out)
+	}
+	printSources(e.Sources, opts.raw, r)
+	return nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+func recordTestHistory(t *testing.T, results ...*askdocs.Result) {
+	t.Helper()
+	for _, res := range results {
+		if _, err := recordHistory(res, nil); err != nil {
+			t.Fatalf("recordHistory() error: %v", err)
+		}
+	}
+}
+
+func TestRecordAndLoadHistory(t *testing.T) {
+	withTempCacheDir(t)
+
+	entries, err := loadHistory()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("loadHistory() on empty history = %v, %v", entries, err)
+	}
+
+	recordTestHistory(t, &askdocs.Result{
+		Query:          "How do I fork?",
+		Version:        "free-pro-team@latest",
+		Answer:         "Use forks.",
+		ConversationID: "conv-1",
+		Sources:        []askdocs.Source{{Title: "Forks", URL: "https://docs.github.com/forks"}},
+	})
+	id, err := recordHistory(&askdocs.Result{Query: "broken", Partial: true, Answer: "Half"}, errors.New("stream cut"))
+	if err != nil {
+		t.Fatalf("recordHistory() error: %v", err)
+	}
+	if id != 2 {
+		t.Errorf("second entry ID = %d, want 2", id)
+	}
+
+	entries, err = loadHistory()
+	if err != nil {
+		t.Fatalf("loadHistory() error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("loadHistory() returned %d entries, want 2", len(entries))
+	}
+	first := entries[0]
+	if first.ID != 1 || first.ConversationID != "conv-1" || len(first.Sources) != 1 || first.AskedAt.IsZero() {
+		t.Errorf("first entry = %+v", first)
+	}
+	if entries[1].Error != "stream cut" || !entries[1].Partial {
+		t.Errorf("second entry = %+v, want the error and partial flag recorded", entries[1])
+	}
+}
+
+func TestRecordHistoryConcurrent(t *testing.T) {
+	withTempCacheDir(t)
+
+	const n = 20
+	ids := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := recordHistory(&askdocs.Result{Query: fmt.Sprintf("q%d", i)}, nil)
+			if err != nil {
+				t.Errorf("recordHistory() error: %v", err)
+			}
+			ids[i] = id
+		}()
+	}
+	wg.Wait()
+
+	slices.Sort(ids)
+	for i, id := range ids {
+		if id != i+1 {
+			t.Fatalf("IDs = %v, want 1 to %d without duplicates", ids, n)
+		}
+	}
+}
+
+func TestLastHistoryID(t *testing.T) {
+	withTempCacheDir(t)
+	path, err := historyPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An answer longer than a read chunk, then a line cut short by a crash.
+	recordTestHistory(t, &askdocs.Result{Query: "short"}, &askdocs.Result{Query: "long", Answer: strings.Repeat("a", 200*1024)})
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":3,"query":"cut`)
+	f.Close()
+
+	id, err := recordHistory(&askdocs.Result{Query: "next"}, nil)
+	if err != nil || id != 3 {
+		t.Errorf("recordHistory() = %d, %v; want ID 3 after the last whole entry", id, err)
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	withTempCacheDir(t)
+	orig := historyLimit
+	historyLimit = 5
+	t.Cleanup(func() { historyLimit = orig })
+
+	for i := range 12 {
+		recordTestHistory(t, &askdocs.Result{Query: fmt.Sprintf("q%d", i+1)})
+	}
+	entries, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 || entries[0].ID != 8 || entries[4].ID != 12 || entries[4].Query != "q12" {
+		t.Errorf("entries after pruning = %+v, want #8 to #12", entries)
+	}
+	if id, _ := recordHistory(&askdocs.Result{Query: "q13"}, nil); id != 13 {
+		t.Errorf("ID after pruning = %d, want 13", id)
+	}
+}
+
+func TestSearchHistory(t *testing.T) {
+	entries := []historyEntry{
+		{ID: 1, Query: "Configure SAML SSO", Answer: "Use the enterprise settings."},
+		{ID: 2, Query: "Fork a repo", Answer: "Click Fork."},
+		{ID: 3, Query: "SAML for GHES", Answer: "Enterprise settings again."},
+	}
+
+	tests := []struct {
+		terms []string
+		want  []int
+	}{
+		{[]string{"saml"}, []int{3, 1}},
+		{[]string{"SAML", "ghes"}, []int{3}},
+		{[]string{"enterprise", "settings"}, []int{3, 1}},
+		{[]string{"click"}, []int{2}},
+		{[]string{"nothing"}, nil},
+	}
+	for _, tt := range tests {
+		var got []int
+		for _, e := range searchHistory(entries, tt.terms) {
+			got = append(got, e.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("searchHistory(%v) = %v, want %v", tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestParseHistoryID(t *testing.T) {
+	for in, want := range map[string]int{"3": 3, "#12": 12} {
+		if got, err := parseHistoryID(in); err != nil || got != want {
+			t.Errorf("parseHistoryID(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "abc", "0", "-1"} {
+		if _, err := parseHistoryID(in); err == nil {
+			t.Errorf("parseHistoryID(%q) should fail", in)
+		}
+	}
+}
+
+func TestRerun(t *testing.T) {
+	withTempCacheDir(t)
+	recordTestHistory(t, &askdocs.Result{Query: "How do I configure SAML?", Version: "enterprise-server@3.19"})
+
+	opts, err := parseArgs(defaultOptions(), []string{"--rerun", "1"})
+	if err != nil {
+		t.Fatalf("parseArgs() error: %v", err)
+	}
+	if opts.query != "How do I configure SAML?" || opts.version != "enterprise-server@3.19" || !opts.refresh {
+		t.Errorf("--rerun options = %+v", opts)
+	}
+
+	opts, err = parseArgs(defaultOptions(), []string{"ask", "--rerun", "1", "--version", "free-pro-team"})
+	if err != nil {
+		t.Fatalf("parseArgs() error: %v", err)
+	}
+	if opts.version != "free-pro-team" {
+		t.Errorf("version = %q, an explicit --version should win", opts.version)
+	}
+
+	if _, err := parseArgs(defaultOptions(), []string{"--rerun", "1", "another", "question"}); err == nil {
+		t.Error("--rerun with a question should fail")
+	}
+	if _, err := parseArgs(defaultOptions(), []string{"--rerun", "9"}); err == nil || !strings.Contains(err.Error(), "#9") {
+		t.Errorf("--rerun with an unknown ID error = %v", err)
+	}
+}
+
+func TestRunAskRecordsHistory(t *testing.T) {
+	withTempCacheDir(t)
+	server, _ := newSearchServer(t, forkAnswer, nil)
+
+	opts := defaultOptions()
+	opts.query = "How do I fork?"
+	opts.format = formatJSON
+	opts.theme = "dark"
+	opts.endpoint = server.URL
+	runAskJSON(t, opts)
+	runAskJSON(t, opts) // served from the cache
+
+	opts.noHistory = true
+	runAskJSON(t, opts)
+
+	entries, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("history has %d entries, want 2", len(entries))
+	}
+	if entries[0].Answer != "Use forks." || entries[0].Cached || !entries[1].Cached {
+		t.Errorf("history = %+v", entries)
+	}
+}
+
+// runHistory runs `history` with args through the command tree and returns
+// its output.
+func runHistory(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var err error
+	out := captureStdout(t, func() {
+		root := newRootCmd(defaultOptions(), func(options) error { return nil })
+		root.SetArgs(append([]string{"history"}, args...))
+		root.SetOut(io.Discard)
+		err = root.Execute()
+	})
+	return out, err
+}
+
+func TestHistoryCommand(t *testing.T) {
+	withTempCacheDir(t)
+	recordTestHistory(t,
+		&askdocs.Result{Query: "Configure SAML", Version: "enterprise-cloud@latest", Answer: "Settings."},
+		&askdocs.Result{Query: "Fork a repo", Version: "free-pro-team@latest", Answer: "Click **Fork**."},
+	)
+
+	out, err := runHistory(t)
+	if err != nil {
+		t.Fatalf("history error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "Fork a repo") || !strings.HasPrefix(strings.TrimSpace(lines[1]), "1 ") {
+		t.Errorf("history should list newest first, got:\n%s", out)
+	}
+
+	out, _ = runHistory(t, "--limit", "1")
+	if strings.Count(out, "\n") != 1 {
+		t.Errorf("--limit 1 listed:\n%s", out)
+	}
+
+	out, _ = runHistory(t, "search", "saml")
+	if !strings.Contains(out, "Configure SAML") || strings.Contains(out, "Fork") {
+		t.Errorf("search saml =\n%s", out)
+	}
+
+	out, err = runHistory(t, "show", "#2", "--no-render")
+	if err != nil {
+		t.Fatalf("history show error: %v", err)
+	}
+	if !strings.Contains(out, "## Fork a repo") || !strings.Contains(out, "Click **Fork**.") {
+		t.Errorf("history show =\n%s", out)
+	}
+
+	out, err = runHistory(t, "show", "1", "--format", "json")
+	if err != nil {
+		t.Fatalf("history show --format json error: %v", err)
+	}
+	var entries []historyEntry
+	if err := json.Unmarshal([]byte(out), &entries); err != nil || len(entries) != 1 || entries[0].Query != "Configure SAML" {
+		t.Errorf("history show --format json = %q (%v)", out, err)
+	}
+
+	if _, err := runHistory(t, "show", "7"); err == nil {
+		t.Error("history show of an unknown entry should fail")
+	}
+
+	if _, err := runHistory(t, "clear"); err != nil {
+		t.Fatalf("history clear error: %v", err)
+	}
+	if out, _ := runHistory(t); out != "" {
+		t.Errorf("history after clear =\n%s", out)
+	}
+}
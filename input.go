@@ -0,0 +1,212 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Limits on what is sent as the question. Longer questions are cut at the
+// end; longer --context keeps its end, where logs usually show the error.
+const (
+	maxQuestionBytes = 16 * 1024
+	maxContextBytes  = 32 * 1024
+)
+
+// questionStdin is the query that reads the question from stdin.
+const questionStdin = "-"
+
+// editorScissors ends the question in the --editor template; everything from
+// it down is dropped, so Markdown headings survive.
+const editorScissors = "# ------------------------ >8 ------------------------"
+
+// editorHelp follows the question in the --editor template.
+const editorHelp = editorScissors + `
+# Write your question above this line. Code blocks and logs are fine.
+# Everything below it is ignored; an empty question cancels.
+`
+
+// readQuestion fills in opts.query from where the flags say: - reads stdin,
+// --file a file (or - for stdin) and --editor opens $EDITOR on the question
+// given so far. Without a question, piped stdin is the question. --context
+// appends a file, or - for stdin, to the question, so `gh run view
+// --log-failed | gh ask-docs --context - "why?"` works. Stdin is never read
+// for context unless asked for: callers such as editors may leave it open.
+func readQuestion(opts *options) error {
+	switch {
+	case opts.file != "" && opts.query != "":
+		return errors.New("--file cannot be combined with a question")
+	case opts.file != "" && opts.editor:
+		return errors.New("--file cannot be combined with --editor")
+	case opts.chat && (opts.query == questionStdin || opts.file == questionStdin || opts.context == questionStdin):
+		return errors.New("chat reads its questions from stdin; pass the first one as an argument or with --file <path>")
+	case opts.context == questionStdin && (opts.query == questionStdin || opts.file == questionStdin):
+		return errors.New("--context - cannot be combined with a question read from stdin")
+	}
+
+	piped := !opts.chat && opts.rerun == 0 && opts.query == "" && opts.file == "" && !opts.editor && opts.context == "" && stdinPiped()
+	switch {
+	case opts.query == questionStdin || opts.file == questionStdin || piped:
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Fprintln(os.Stderr, "Reading the question from stdin (Ctrl-D to finish)")
+		}
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("reading the question from stdin: %w", err)
+		}
+		opts.query = string(data)
+	case opts.file != "":
+		data, err := os.ReadFile(opts.file)
+		if err != nil {
+			return err
+		}
+		opts.query = string(data)
+	case opts.editor:
+		query, err := editQuestion(opts.query)
+		if err != nil {
+			return err
+		}
+		opts.query = query
+	}
+
+	opts.query = truncateQuestion(strings.TrimSpace(opts.query))
+	if opts.context == "" {
+		return nil
+	}
+	if opts.query == "" {
+		return errors.New("--context needs a question to go with it")
+	}
+	var data []byte
+	var err error
+	if opts.context == questionStdin {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(opts.context)
+	}
+	if err != nil {
+		return fmt.Errorf("reading --context: %w", err)
+	}
+	opts.query = withContext(opts.query, truncateContext(string(data)))
+	return nil
+}
+
+// stdinPiped reports whether stdin is a pipe or a file rather than a terminal
+// or /dev/null.
+func stdinPiped() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice == 0
+}
+
+// truncateQuestion cuts a question longer than maxQuestionBytes, with a
+// warning on stderr.
+func truncateQuestion(q string) string {
+	if len(q) <= maxQuestionBytes {
+		return q
+	}
+	fmt.Fprintf(os.Stderr, "⚠️  the question is %s; only the first %s is sent\n", formatBytes(len(q)), formatBytes(maxQuestionBytes))
+	cut := maxQuestionBytes
+	for cut > 0 && !utf8.RuneStart(q[cut]) {
+		cut--
+	}
+	return q[:cut]
+}
+
+// truncateContext keeps the last maxContextBytes of --context, starting
+// at a line boundary, with a warning on stderr.
+func truncateContext(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= maxContextBytes {
+		return s
+	}
+	fmt.Fprintf(os.Stderr, "⚠️  the context is %s; only the last %s is sent\n", formatBytes(len(s)), formatBytes(maxContextBytes))
+	tail := s[len(s)-maxContextBytes:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 {
+		return tail[i+1:]
+	}
+	for len(tail) > 0 && !utf8.RuneStart(tail[0]) {
+		tail = tail[1:]
+	}
+	return tail
+}
+
+// withContext appends context to the question as a fenced code block.
+func withContext(query, context string) string {
+	if context == "" {
+		return query
+	}
+	return query + "\n\n" + fenced(context, "")
+}
+
+// fenced puts s in a code block, fenced with more backticks than any run
+// inside it.
+func fenced(s, lang string) string {
+	fence := "```"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + s + "\n" + fence
+}
+
+// formatBytes prints a size in bytes or KiB.
+func formatBytes(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d bytes", n)
+	}
+	return fmt.Sprintf("%d KiB", (n+1023)/1024)
+}
+
+// editorCommand returns the editor gh would use: GH_EDITOR, VISUAL or EDITOR,
+// else nano (notepad on Windows).
+func editorCommand() string {
+	def := "nano"
+	if runtime.GOOS == "windows" {
+		def = "notepad"
+	}
+	return cmp.Or(os.Getenv("GH_EDITOR"), os.Getenv("VISUAL"), os.Getenv("EDITOR"), def)
+}
+
+// editQuestion opens the editor on a template holding initial and returns
+// what was written above the scissors line.
+func editQuestion(initial string) (string, error) {
+	dir, err := os.MkdirTemp("", "gh-ask-docs-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "QUESTION.md")
+	template := initial + "\n\n" + editorHelp
+	if initial == "" {
+		template = "\n" + editorHelp
+	}
+	if err := os.WriteFile(path, []byte(template), 0o600); err != nil {
+		return "", err
+	}
+
+	args := strings.Fields(editorCommand())
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running editor %q: %w", args[0], err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	query, _, _ := strings.Cut(string(data), editorScissors)
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", errors.New("aborted: the question is empty")
+	}
+	return query, nil
+}
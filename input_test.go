@@ -0,0 +1,201 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withStdin replaces stdin with a file holding data while the test runs.
+func withStdin(t *testing.T, data string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	writeFile(t, path, data)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() {
+		os.Stdin = orig
+		f.Close()
+	})
+}
+
+func TestReadQuestion(t *testing.T) {
+	questionFile := filepath.Join(t.TempDir(), "question.md")
+	writeFile(t, questionFile, "# Runners\n\nHow do I add a self-hosted runner?\n")
+	contextFile := filepath.Join(t.TempDir(), "build.log")
+	writeFile(t, contextFile, "Error: exit code 2\n")
+
+	tests := []struct {
+		name  string
+		stdin string // "" leaves stdin alone
+		opts  func(o *options)
+		want  string
+	}{
+		{
+			"arguments",
+			"",
+			func(o *options) { o.query = "  how do I fork?  " },
+			"how do I fork?",
+		},
+		{
+			"dash reads stdin",
+			"What is GHAS?\n",
+			func(o *options) { o.query = "-" },
+			"What is GHAS?",
+		},
+		{
+			"piped question",
+			"What is GHAS?\n",
+			func(o *options) {},
+			"What is GHAS?",
+		},
+		{
+			"file",
+			"",
+			func(o *options) { o.file = questionFile },
+			"# Runners\n\nHow do I add a self-hosted runner?",
+		},
+		{
+			"file from stdin",
+			"What is GHAS?",
+			func(o *options) { o.file = "-" },
+			"What is GHAS?",
+		},
+		{
+			"context from stdin",
+			"Error: exit code 1\n",
+			func(o *options) { o.query = "why did this fail?"; o.context = "-" },
+			"why did this fail?\n\n```\nError: exit code 1\n```",
+		},
+		{
+			"context from a file",
+			"",
+			func(o *options) { o.query = "why did this fail?"; o.context = contextFile },
+			"why did this fail?\n\n```\nError: exit code 2\n```",
+		},
+		{
+			"context with a code fence",
+			"```yaml\non: push\n```\n",
+			func(o *options) { o.query = "what is wrong?"; o.context = "-" },
+			"what is wrong?\n\n````\n```yaml\non: push\n```\n````",
+		},
+		{
+			"chat keeps stdin",
+			"/exit\n",
+			func(o *options) { o.query = "hi"; o.chat = true },
+			"hi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stdin != "" {
+				withStdin(t, tt.stdin)
+			}
+			opts := defaultOptions()
+			tt.opts(&opts)
+			if err := readQuestion(&opts); err != nil {
+				t.Fatalf("readQuestion() error: %v", err)
+			}
+			if opts.query != tt.want {
+				t.Errorf("query = %q, want %q", opts.query, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadQuestionLeavesStdin(t *testing.T) {
+	withStdin(t, "input meant for something else\n")
+
+	// Without --context, a question never reads stdin, which callers such as
+	// editors and CI runners may leave open without writing to it.
+	opts := defaultOptions()
+	opts.query = "how do I fork?"
+	if err := readQuestion(&opts); err != nil {
+		t.Fatalf("readQuestion() error: %v", err)
+	}
+	if opts.query != "how do I fork?" {
+		t.Errorf("query = %q, want stdin left out", opts.query)
+	}
+	if off, err := os.Stdin.Seek(0, io.SeekCurrent); err != nil || off != 0 {
+		t.Errorf("stdin offset = %d, %v; want stdin unread", off, err)
+	}
+}
+
+func TestReadQuestionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(o *options)
+		wantErr string
+	}{
+		{"file and question", func(o *options) { o.file = "q.md"; o.query = "q" }, "cannot be combined with a question"},
+		{"file and editor", func(o *options) { o.file = "q.md"; o.editor = true }, "cannot be combined with --editor"},
+		{"chat from stdin", func(o *options) { o.query = "-"; o.chat = true }, "chat reads its questions from stdin"},
+		{"chat context from stdin", func(o *options) { o.query = "q"; o.context = "-"; o.chat = true }, "chat reads its questions from stdin"},
+		{"stdin twice", func(o *options) { o.query = "-"; o.context = "-" }, "cannot be combined with a question read from stdin"},
+		{"context without a question", func(o *options) { o.context = filepath.Join(t.TempDir(), "build.log") }, "needs a question"},
+		{"missing context", func(o *options) { o.query = "q"; o.context = filepath.Join(t.TempDir(), "missing.log") }, "missing.log"},
+		{"missing file", func(o *options) { o.file = filepath.Join(t.TempDir(), "missing.md") }, "missing.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions()
+			tt.opts(&opts)
+			if err := readQuestion(&opts); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readQuestion() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadQuestionTruncates(t *testing.T) {
+	withStdin(t, "first line\n"+strings.Repeat("log line\n", maxContextBytes/9+100)+"Error: boom\n")
+
+	opts := defaultOptions()
+	opts.query = strings.Repeat("é", maxQuestionBytes)
+	opts.context = "-"
+	var err error
+	stderr := captureStderr(t, func() { err = readQuestion(&opts) })
+	if err != nil {
+		t.Fatalf("readQuestion() error: %v", err)
+	}
+
+	question, context, _ := strings.Cut(opts.query, "\n\n```\n")
+	if len(question) > maxQuestionBytes || !strings.HasPrefix(question, "éé") || strings.ContainsRune(question, '�') {
+		t.Errorf("question was not cut at a character boundary within the limit (%d bytes)", len(question))
+	}
+	if len(context) > maxContextBytes+4 || strings.Contains(context, "first line") || !strings.HasSuffix(context, "Error: boom\n```") {
+		t.Errorf("context should keep the end of the input, got %d bytes ending %q", len(context), context[len(context)-20:])
+	}
+	if !strings.Contains(stderr, "the question is") || !strings.Contains(stderr, "only the last 32 KiB is sent") {
+		t.Errorf("warnings = %q", stderr)
+	}
+}
+
+func TestEditQuestion(t *testing.T) {
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	writeFile(t, editor, "#!/bin/sh\n{ printf '# Heading\\n\\n'; cat \"$1\"; } > \"$1.new\" && mv \"$1.new\" \"$1\"\n")
+	if err := os.Chmod(editor, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GH_EDITOR", editor)
+
+	got, err := editQuestion("initial question")
+	if err != nil {
+		t.Fatalf("editQuestion() error: %v", err)
+	}
+	if want := "# Heading\n\ninitial question"; got != want {
+		t.Errorf("editQuestion() = %q, want %q", got, want)
+	}
+
+	t.Setenv("GH_EDITOR", "true")
+	if _, err := editQuestion(""); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("editQuestion() with an untouched template error = %v, want it to be empty", err)
+	}
+}
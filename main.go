@@ -8,17 +8,22 @@
 // Usage:
 //
 //	gh ask-docs [flags] <query>
+//	gh ask-docs [flags] - | --file <path> | --editor
+//	gh ask-docs --about <path> [query]
+//	gh ask-docs --compare <version>,<version>... <query>
+//	gh ask-docs ask [flags] <query>
+//	gh ask-docs chat [flags] [query]
+//	gh ask-docs versions [refresh]
+//	gh ask-docs config get|set|list
+//	gh ask-docs cache ls|clear|prune
+//	gh ask-docs history [search|show|clear]
+//	gh ask-docs export <history-id>
+//	gh ask-docs batch <file>
+//	gh ask-docs explain-run <run-id>
+//	gh ask-docs index [docs-dir]
+//	gh ask-docs completion bash|zsh|fish|powershell
 //
-// Flags:
-//
-//	--version     docs version (free-pro-team, enterprise-cloud,
-//	              or enterprise-server@<3.13-3.17>)
-//	--sources     display reference links
-//	--no-render   stream raw Markdown (default renders with Glamour)
-//	--no-stream   don't stream answer, only print only when complete (stdout-friendly)
-//	--wrap        word-wrap width when rendering (0 = no wrap)
-//	--theme       color theme: auto (default), light, dark
-//	--debug       show raw NDJSON from the API
+// Run `gh ask-docs --help` for the flags; they are defined in newRootCmd.
 //
 // Notes:
 //
@@ -26,322 +31,482 @@
 //     an extremely large wrap width.
 //   - When wrapping is disabled the terminal may visually wrap long lines.  The
 //     spinner logic counts **visual** lines so frames clear cleanly.
+//   - The conversation ID of every answer is saved per shell session (see
+//     sessionKey) so `--continue` can send it back for follow-up questions.
+//   - Complete answers to new questions are cached under the user cache
+//     directory (see cacheKey) and reused until --cache-ttl expires.
+//   - Defaults for version, language, theme, wrap, sources, format, endpoint,
+//     offline and docs-dir come from ~/.config/gh-ask-docs/config.yml, then
+//     .gh-ask-docs.yml in the repository, then GH_ASK_DOCS_* environment
+//     variables; flags override them all.
 //   - All spinner frames and debugging data are written to STDERR so STDOUT can
 //     be safely piped.
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
-	"strconv"
+	"os/signal"
 	"strings"
-
-	"github.com/charmbracelet/glamour"
+	"time"
 
 	"github.com/Ebonsignori/gh-ask-docs/askdocs"
 )
 
-const endpoint = "https://docs.github.com/api/ai-search/v1"
+// options holds everything parsed from the command line.
+type options struct {
+	query          string
+	version        string
+	showSources    bool
+	raw            bool
+	noStream       bool
+	wrapWidth      int
+	theme          string
+	debug          bool
+	listVersions   bool
+	showHelp       bool
+	continueConv   bool
+	conversationID string
+	chat           bool
+	format         string
+	retries        int
+	retryBackoff   time.Duration
+	keepPartial    bool
+	timeout        time.Duration
+	idleTimeout    time.Duration
+	endpoint       string
+	headers        []string
+	clientName     string
+	noCache        bool
+	refresh        bool
+	cacheTTL       time.Duration
+	noHistory      bool
+	rerun          int
+	output         string
+	exportFormat   string
+	comment        string
+	dryRun         bool
+	compare        []string
+	strictVersion  bool
+	language       string
+	file           string
+	context        string
+	editor         bool
+	about          string
+	offline        bool
+	docsDir        string
+}
 
-// parseArgs manually parses command line arguments to allow flags anywhere
-func parseArgs(args []string) (query string, version string, showSources bool, raw bool, noStream bool, wrapWidth int, theme string, debug bool, listVersions bool, showHelp bool) {
-	// Set defaults
-	version = "free-pro-team"
-	theme = "auto"
+// defaultOptions returns the options used when no flags are given.
+func defaultOptions() options {
+	return options{
+		version:      "free-pro-team",
+		language:     languageAuto,
+		theme:        "auto",
+		format:       formatText,
+		retries:      2,
+		retryBackoff: askdocs.DefaultRetryBackoff,
+		idleTimeout:  askdocs.DefaultIdleTimeout,
+		cacheTTL:     defaultCacheTTL,
+	}
+}
 
-	var queryParts []string
+func main() {
+	//----------------------------------------------------------------------
+	// Defaults: built-in < user config < repo config < env < flags
+	//----------------------------------------------------------------------
+	base, cfgErr := configuredOptions()
+	if path, err := refreshedVersionsPath(); err == nil {
+		askdocs.RefreshedVersionsPath = path
+	}
 
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
+	root := newRootCmd(base, runQuestion)
+	requireConfig(root, cfgErr)
+	if err := root.Execute(); err != nil {
+		askdocs.Exit(err)
+	}
+}
 
-		switch {
-		case arg == "--help" || arg == "-h":
-			showHelp = true
-		case arg == "--version":
-			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				i++
-				version = args[i]
-			}
-		case strings.HasPrefix(arg, "--version="):
-			version = strings.TrimPrefix(arg, "--version=")
-		case arg == "--sources":
-			showSources = true
-		case arg == "--no-render":
-			raw = true
-		case arg == "--no-stream":
-			noStream = true
-		case arg == "--wrap":
-			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				i++
-				if w, err := strconv.Atoi(args[i]); err == nil {
-					wrapWidth = w
-				}
-			}
-		case strings.HasPrefix(arg, "--wrap="):
-			if w, err := strconv.Atoi(strings.TrimPrefix(arg, "--wrap=")); err == nil {
-				wrapWidth = w
-			}
-		case arg == "--theme":
-			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				i++
-				theme = args[i]
-			}
-		case strings.HasPrefix(arg, "--theme="):
-			theme = strings.TrimPrefix(arg, "--theme=")
-		case arg == "--debug":
-			debug = true
-		case arg == "--list-versions":
-			listVersions = true
-		case strings.HasPrefix(arg, "-"):
-			// Unknown flag, ignore for now
-		default:
-			// This is part of the query
-			queryParts = append(queryParts, arg)
+// runQuestion dispatches the root, `ask` and `chat` commands.
+func runQuestion(opts options) error {
+	if opts.listVersions {
+		return runVersions(opts)
+	}
+	if err := readQuestion(&opts); err != nil {
+		return err
+	}
+	if opts.about != "" {
+		if err := addAboutFile(&opts); err != nil {
+			return err
 		}
 	}
-
-	query = strings.Join(queryParts, " ")
-	return
+	if err := resolveAutoVersion(&opts); err != nil {
+		return err
+	}
+	if err := resolveLanguage(&opts); err != nil {
+		return err
+	}
+	if len(opts.compare) > 0 {
+		if opts.query == "" {
+			return errMissingQuery
+		}
+		return runCompare(opts)
+	}
+	if opts.chat {
+		return runChat(opts)
+	}
+	if opts.query == "" {
+		return errMissingQuery
+	}
+	return runAsk(opts)
 }
 
-func printUsage() {
-	bin := filepath.Base(os.Args[0])
-	if strings.HasPrefix(bin, "gh-") {
-		bin = "gh " + strings.TrimPrefix(bin, "gh-")
-	}
-	fmt.Fprintf(os.Stderr, "usage: %s [flags] <query>\n\n", bin)
-	fmt.Fprintf(os.Stderr, "Flags:\n")
-	fmt.Fprintf(os.Stderr, "  --version string     docs version (default \"free-pro-team\")\n")
-	fmt.Fprintf(os.Stderr, "  --sources           show reference links after answer\n")
-	fmt.Fprintf(os.Stderr, "  --no-render         stream raw Markdown without Glamour\n")
-	fmt.Fprintf(os.Stderr, "  --no-stream         Don't stream answer, print only when complete\n")
-	fmt.Fprintf(os.Stderr, "  --wrap int          word-wrap width for rendered output (0 = no wrap)\n")
-	fmt.Fprintf(os.Stderr, "  --theme string      color theme: auto, light, dark (default \"auto\")\n")
-	fmt.Fprintf(os.Stderr, "  --debug             print raw NDJSON for troubleshooting\n")
-	fmt.Fprintf(os.Stderr, "  --list-versions     list supported enterprise server versions\n")
-	fmt.Fprintf(os.Stderr, "  --help, -h          show this help message\n")
-}
+// runVersions lists the docs versions with the dates of each GHES release,
+// flagging releases that are deprecated soon.
+func runVersions(opts options) error {
+	versions, err := askdocs.LoadSupportedVersions()
+	if err != nil {
+		return fmt.Errorf("loading supported versions: %w", err)
+	}
+	now := time.Now()
+	list := versions.List(now)
 
-func main() {
-	//----------------------------------------------------------------------
-	// Parse arguments manually to allow flags anywhere
-	//----------------------------------------------------------------------
-	query, version, showSources, raw, noStream, wrapWidth, theme, debug, listVersions, showHelp := parseArgs(os.Args[1:])
+	source := "built in"
+	if versions.Refreshed {
+		source = "refreshed from github/docs"
+	}
 
-	if showHelp {
-		printUsage()
-		os.Exit(0)
+	if opts.format == formatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(struct {
+			LastUpdated string                `json:"lastUpdated"`
+			Source      string                `json:"source"`
+			Versions    []askdocs.VersionInfo `json:"versions"`
+		}{versions.LastUpdated, source, list})
 	}
 
-	if listVersions {
-		versions, err := askdocs.LoadSupportedVersions()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading supported versions: %v\n", err)
-			fmt.Fprintf(os.Stderr, "Fallback supported versions: 3.11, 3.12, 3.13, 3.14, 3.15, 3.16, 3.17\n")
-			os.Exit(1)
+	fmt.Println("Docs versions:")
+	fmt.Println()
+	for _, v := range list {
+		detail := v.Name
+		if v.ReleaseDate != "" {
+			detail = fmt.Sprintf("released %s, deprecated %s", v.ReleaseDate, v.DeprecationDate)
 		}
-
-		fmt.Println("Supported GitHub Enterprise Server versions:")
-		for _, v := range versions.SupportedVersions {
-			if v == versions.LatestVersion {
-				fmt.Printf("  %s (latest)\n", v)
-			} else {
-				fmt.Printf("  %s\n", v)
-			}
+		if v.Latest && v.Plan == askdocs.PlanEnterpriseServer {
+			detail += " (latest)"
+		}
+		deprecated, _ := time.Parse(time.DateOnly, v.DeprecationDate)
+		switch {
+		case v.Deprecated:
+			detail += fmt.Sprintf("  ⚠️  deprecated %d days ago", int(now.Sub(deprecated).Hours()/24))
+		case v.DeprecatingSoon:
+			detail += fmt.Sprintf("  ⚠️  deprecated in %d days", int(deprecated.Sub(now).Hours()/24))
 		}
-		fmt.Printf("\nLast updated: %s\n", versions.LastUpdated)
-		fmt.Println("\nUsage: gh ask-docs --version enterprise-server@<version> <query>")
-		os.Exit(0)
+		fmt.Printf("  %-26s %s\n", v.Version, detail)
 	}
 
-	if query == "" {
-		printUsage()
-		os.Exit(1)
+	fmt.Printf("\nLast updated: %s (%s)\n", versions.LastUpdated, source)
+	fmt.Println("\nUsage: gh ask-docs --version enterprise-server@<version> <query>")
+	return nil
+}
+
+// runAsk answers a single question.
+func runAsk(opts options) error {
+	version, err := docsVersion(opts.version, opts)
+	if err != nil {
+		return err
 	}
 
-	version = askdocs.NormalizeVersion(version)
+	if opts.comment != "" {
+		if _, err := parseCommentTarget(opts.comment); err != nil {
+			return err
+		}
+	}
 
 	//----------------------------------------------------------------------
-	// HTTP Request
+	// Conversation
 	//----------------------------------------------------------------------
-	payload, err := json.Marshal(map[string]string{
-		"query":       query,
-		"version":     version,
-		"language":    "en",
-		"client_name": "gh-ask-docs",
-	})
+	conversationID, err := resolveConversationID(opts, version)
 	if err != nil {
-		askdocs.Fatal(err)
+		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	//----------------------------------------------------------------------
+	// Renderers
+	//----------------------------------------------------------------------
+	r, err := newRenderers(opts.theme, opts.wrapWidth)
 	if err != nil {
-		askdocs.Fatal(err)
+		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/x-ndjson")
 
-	resp, err := (&http.Client{Timeout: 0}).Do(req)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		askdocs.ExitCouldNotAnswer()
+	//----------------------------------------------------------------------
+	// Ask
+	//----------------------------------------------------------------------
+	ctx, cancel := askContext(opts)
+	defer cancel()
+
+	client, err := newAsker(opts)
+	if err != nil {
+		return err
+	}
+
+	q := askdocs.Query{
+		Query:          opts.query,
+		Version:        version,
+		Language:       opts.language,
+		ConversationID: conversationID,
 	}
-	defer resp.Body.Close()
 
 	//----------------------------------------------------------------------
-	// Renderers
+	// Answer cache (follow-ups depend on the conversation and offline answers
+	// are searched locally, so skip them)
 	//----------------------------------------------------------------------
-	var answerR, noWrapR *glamour.TermRenderer
-
-	switch theme {
-	case "auto":
-		// Try auto-detection first, fall back to manual detection if needed
-		answerR = askdocs.NewAutoRenderer(wrapWidth)
-		noWrapR = askdocs.NewAutoRenderer(0)
-
-		// If auto-detection fails, fall back to our improved theme detection
-		if answerR == nil {
-			themeDetected := "dark"
-			if askdocs.IsLight() {
-				themeDetected = "light"
+	useCache := !opts.noCache && !opts.offline && q.ConversationID == ""
+	var stale *cacheEntry
+	if useCache && !opts.refresh {
+		if entry, err := loadCachedAnswer(q); err == nil {
+			if !entry.expired(opts.cacheTTL) {
+				return finishAnswer(cachedResult(entry), opts, r)
 			}
-			answerR = askdocs.NewRenderer(themeDetected, wrapWidth)
-			noWrapR = askdocs.NewRenderer(themeDetected, 0)
+			stale = entry
 		}
-	case "light", "dark":
-		// User explicitly specified theme
-		answerR = askdocs.NewRenderer(theme, wrapWidth)
-		noWrapR = askdocs.NewRenderer(theme, 0)
-	default:
-		fmt.Fprintf(os.Stderr, "Invalid theme '%s'. Use 'auto', 'light', or 'dark'.\n", theme)
-		os.Exit(1)
 	}
 
-	reader := bufio.NewReader(resp.Body)
-
-	var (
-		buf       strings.Builder
-		prevLines int
-		spinIdx   int
-	)
-
-	// Source collection
-	seen := map[string]askdocs.Source{}
-	order := []string{}
-
-	streaming := true
+	res, err := streamAnswer(ctx, client, q, opts, r)
+	if err != nil && stale != nil && errors.Is(err, askdocs.ErrRequestFailed) {
+		// Offline: an old answer beats no answer.
+		fmt.Fprintf(os.Stderr, "⚠️  %s; showing the answer cached %s\n", askdocs.ErrorMessage(err), stale.CachedAt.Format(time.RFC1123))
+		return finishAnswer(cachedResult(stale), opts, r)
+	}
+	if err != nil {
+		addToHistory(res, err, opts)
 
-	for streaming {
-		line, rdErr := reader.ReadBytes('\n')
-		if rdErr == io.EOF {
-			streaming = false
+		// Keep stdout machine-readable; the reason also goes to stderr.
+		if opts.format == formatJSON {
+			res.Error = err.Error()
+			writeJSONResult(res)
 		}
-		trimmed := bytes.TrimSpace(line)
-		if len(trimmed) > 0 {
-			if debug {
-				fmt.Fprintf(os.Stderr, "%s\n", trimmed)
-			}
-			var jl askdocs.GenericLine
-			if json.Unmarshal(trimmed, &jl) == nil {
-				switch jl.ChunkType {
-				case askdocs.ChunkMessage:
-					buf.WriteString(jl.Text)
-					if raw && !noStream {
-						fmt.Print(jl.Text)
-					}
-
-				case askdocs.ChunkSources:
-					var srcs []askdocs.Source
-					if json.Unmarshal(jl.Sources, &srcs) == nil {
-						for _, s := range srcs {
-							if _, ok := seen[s.URL]; !ok {
-								seen[s.URL] = s
-								order = append(order, s.URL)
-							}
-						}
-					}
-
-				case askdocs.ChunkNoContent, askdocs.ChunkInputFilter:
-					askdocs.ExitCouldNotAnswer()
-				}
-			}
+		if res.Partial && opts.format == formatText && opts.showSources {
+			printSources(res.Sources, opts.raw, r)
 		}
+		return err
+	}
 
-		//--------------------------------------------------------------
-		// Frame / Spinner
-		//--------------------------------------------------------------
-		if noStream {
-			askdocs.RenderSpinner(askdocs.SpinnerFrames[spinIdx%len(askdocs.SpinnerFrames)])
-			spinIdx++
-			continue
+	if useCache {
+		if err := storeCachedAnswer(q, res); err != nil && opts.debug {
+			fmt.Fprintf(os.Stderr, "could not cache answer: %v\n", err)
 		}
+	}
 
-		if !raw {
-			askdocs.RenderFrame(answerR, buf.String(), askdocs.SpinnerFrames[spinIdx%len(askdocs.SpinnerFrames)], &prevLines)
-			spinIdx++
-		}
+	return finishAnswer(res, opts, r)
+}
+
+// cachedResult returns the result stored in entry, marked as cached. Its
+// conversation is dropped: it may be from another terminal and up to
+// --cache-ttl old, so --continue must not pick it up.
+func cachedResult(entry *cacheEntry) *askdocs.Result {
+	res := entry.Result
+	res.Cached = true
+	res.ConversationID = ""
+	return res
+}
+
+// finishAnswer records the conversation and history, prints what follows the
+// answer text (the JSON result or the sources) and handles --output and
+// --comment. Cached answers are printed in full first since nothing was
+// streamed.
+func finishAnswer(res *askdocs.Result, opts options, r renderers) error {
+	if res.Cached {
+		writeCachedAnswer(res, opts, r)
+	}
+	addToHistory(res, nil, opts)
 
-		if !streaming && rdErr != nil && rdErr != io.EOF {
-			askdocs.ExitCouldNotAnswer()
+	if res.ConversationID != "" {
+		if err := saveConversation(conversationState{ConversationID: res.ConversationID, Version: res.Version}); err != nil && opts.debug {
+			fmt.Fprintf(os.Stderr, "could not save conversation: %v\n", err)
 		}
 	}
 
 	//----------------------------------------------------------------------
-	// Clear spinner / final repaint
+	// JSON result / Sources
 	//----------------------------------------------------------------------
-	if noStream {
-		fmt.Fprint(os.Stderr, "\r \r")
-	} else if !raw {
-		askdocs.RenderFrame(answerR, buf.String(), ' ', &prevLines)
-		fmt.Println()
+	switch opts.format {
+	case formatJSON:
+		writeJSONResult(res)
+	case formatText:
+		if opts.showSources {
+			printSources(res.Sources, opts.raw, r)
+		}
 	}
 
 	//----------------------------------------------------------------------
-	// Output buffered answer (no-stream mode)
+	// Export / Comment
 	//----------------------------------------------------------------------
-	if noStream {
-		if raw {
-			fmt.Print(buf.String())
-		} else {
-			out, _ := answerR.Render(buf.String())
-			fmt.Print(out)
+	if opts.output != "" {
+		if err := exportToFile(exportFromResult(res), opts.output, opts.exportFormat); err != nil {
+			return fmt.Errorf("writing %s: %w", opts.output, err)
 		}
-		fmt.Println()
 	}
 
-	//----------------------------------------------------------------------
-	// Sources
-	//----------------------------------------------------------------------
-	if showSources && len(order) > 0 {
-		if raw {
-			fmt.Println("\nSources:")
-			for _, u := range order {
-				s := seen[u]
-				if s.Title != "" {
-					fmt.Printf("- %s (%s)\n", s.Title, s.URL)
-				} else {
-					fmt.Printf("- %s\n", s.URL)
-				}
-			}
-			return
+	if opts.comment != "" {
+		target, _ := parseCommentTarget(opts.comment)
+		ctx, cancel := askContext(opts)
+		defer cancel()
+		commentURL, err := postComment(ctx, target, res, opts.dryRun)
+		if err != nil {
+			return err
+		}
+		if commentURL != "" {
+			fmt.Fprintf(os.Stderr, "Commented on %s: %s\n", target, commentURL)
+		}
+	}
+	return nil
+}
+
+// askContext returns the context for a single question: cancelled by Ctrl-C
+// and, when --timeout is set, by the deadline.
+func askContext(opts options) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if opts.timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// newAsker returns what answers questions: the offline index with --offline,
+// else the AI Search client.
+func newAsker(opts options) (askdocs.Asker, error) {
+	if !opts.offline {
+		return newClient(opts)
+	}
+	ix, err := loadOfflineIndex(opts.docsDir)
+	if err != nil {
+		return nil, err
+	}
+	offline := &askdocs.Offline{Index: ix}
+	if opts.debug {
+		offline.Debug = os.Stderr
+	}
+	return offline, nil
+}
+
+// newClient returns an AI Search client configured from opts.
+func newClient(opts options) (*askdocs.Client, error) {
+	client := askdocs.NewClient()
+
+	if opts.endpoint != "" {
+		if err := validateEndpoint(opts.endpoint); err != nil {
+			return nil, err
 		}
+		client.Endpoint = opts.endpoint
+	}
 
-		var md strings.Builder
-		md.WriteString("### Sources\n")
-		for _, u := range order {
-			s := seen[u]
-			text := s.Title
-			if text == "" {
-				text = u
+	if opts.clientName != "" {
+		client.ClientName = opts.clientName
+	}
+
+	if len(opts.headers) > 0 {
+		client.Header = http.Header{}
+		for _, h := range opts.headers {
+			key, value, err := parseHeader(h)
+			if err != nil {
+				return nil, err
 			}
-			md.WriteString(fmt.Sprintf("* %s\n", askdocs.AutoLink(u, text)))
+			client.Header.Add(key, value)
 		}
-		out, _ := noWrapR.Render(md.String())
-		fmt.Print(out)
 	}
+
+	if opts.debug {
+		client.Debug = os.Stderr
+	}
+	client.IdleTimeout = opts.idleTimeout
+	client.Retries = opts.retries
+	client.RetryBackoff = opts.retryBackoff
+	client.OnRetry = func(attempt int, delay time.Duration, err error) {
+		fmt.Fprintf(os.Stderr, "%s; retrying in %s (%d/%d)\n", askdocs.ErrorMessage(err), delay.Round(100*time.Millisecond), attempt, opts.retries)
+	}
+	return client, nil
+}
+
+// validateEndpoint checks that endpoint is an absolute http(s) URL.
+func validateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid endpoint %q: must be an http(s) URL", endpoint)
+	}
+	return nil
+}
+
+// parseHeader splits a --header value of the form "Key: value".
+func parseHeader(h string) (key, value string, err error) {
+	key, value, ok := strings.Cut(h, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", fmt.Errorf("invalid header %q: use \"Key: value\"", h)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+// docsVersion parses a --version value. A version that cannot be used as
+// given is replaced with a warning on stderr, or rejected with
+// --strict-version.
+func docsVersion(v string, opts options) (string, error) {
+	version, err := askdocs.ParseVersion(v)
+	if err != nil {
+		var verr *askdocs.VersionError
+		if opts.strictVersion || !errors.As(err, &verr) {
+			return "", err
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  %s\n    using %s instead\n", err, version)
+	}
+	return version.String(), nil
+}
+
+// languageAuto is the --language value that follows the user's locale.
+const languageAuto = "auto"
+
+// resolveLanguage replaces --language with the docs language code it names,
+// detecting it from LC_ALL, LC_MESSAGES or LANG for auto.
+func resolveLanguage(opts *options) error {
+	if opts.language == languageAuto {
+		opts.language = askdocs.LanguageFromEnv()
+		if opts.debug {
+			fmt.Fprintf(os.Stderr, "detected language %s from the locale\n", opts.language)
+		}
+		return nil
+	}
+	language, err := askdocs.ParseLanguage(opts.language)
+	if err != nil {
+		return err
+	}
+	opts.language = language
+	return nil
+}
+
+// resolveConversationID returns the conversation to continue, if any. It
+// warns when --continue picks up a conversation about another docs version
+// than the one asked about now.
+func resolveConversationID(opts options, version string) (string, error) {
+	if opts.conversationID != "" || !opts.continueConv {
+		return opts.conversationID, nil
+	}
+	state, err := loadConversation()
+	if err != nil {
+		return "", fmt.Errorf("no previous conversation to continue: %w", err)
+	}
+	if state.Version != "" && state.Version != version {
+		fmt.Fprintf(os.Stderr, "⚠️  continuing a conversation about %s, but this question is for %s\n", state.Version, version)
+	}
+	return state.ConversationID, nil
 }
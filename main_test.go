@@ -3,11 +3,13 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -19,10 +21,10 @@ func TestMainFunctionality(t *testing.T) {
 	// Since main() calls os.Exit, we can't test it directly
 	// Instead, we'll test the core logic by extracting testable parts
 	t.Run("endpoint constant", func(t *testing.T) {
-		if endpoint == "" {
+		if askdocs.DefaultEndpoint == "" {
 			t.Error("endpoint should not be empty")
 		}
-		if !strings.HasPrefix(endpoint, "https://") {
+		if !strings.HasPrefix(askdocs.DefaultEndpoint, "https://") {
 			t.Error("endpoint should be HTTPS")
 		}
 	})
@@ -320,7 +322,7 @@ func TestHTTPRequestCreation(t *testing.T) {
 		"client_name": "gh-ask-docs",
 	})
 
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	req, err := http.NewRequest(http.MethodPost, askdocs.DefaultEndpoint, bytes.NewReader(payload))
 	if err != nil {
 		t.Fatalf("Failed to create request: %v", err)
 	}
@@ -333,8 +335,8 @@ func TestHTTPRequestCreation(t *testing.T) {
 		t.Errorf("Request method = %q, want %q", req.Method, http.MethodPost)
 	}
 
-	if req.URL.String() != endpoint {
-		t.Errorf("Request URL = %q, want %q", req.URL.String(), endpoint)
+	if req.URL.String() != askdocs.DefaultEndpoint {
+		t.Errorf("Request URL = %q, want %q", req.URL.String(), askdocs.DefaultEndpoint)
 	}
 
 	if req.Header.Get("Content-Type") != "application/json" {
@@ -473,14 +475,24 @@ func TestMockHTTPServer(t *testing.T) {
 }
 
 func TestVersionNormalization(t *testing.T) {
-	// Test the version normalization used in main
+	// Test the version normalization used in main. Enterprise Server
+	// releases drop out of the supported list over time (3.15 did, which
+	// broke a hard-coded fixture), so the releases come from the list.
+	versions, err := askdocs.LoadSupportedVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	supported := "enterprise-server@" + versions.SupportedVersions[0]
+	latest := "enterprise-server@" + versions.LatestVersion
+
 	tests := []struct {
 		input    string
 		expected string
 	}{
 		{"free-pro-team", "free-pro-team@latest"},
 		{"enterprise-cloud", "enterprise-cloud@latest"},
-		{"enterprise-server@3.15", "enterprise-server@3.15"},
+		{supported, supported},
+		{"enterprise-server@3.15", latest},
 		{"invalid", "free-pro-team@latest"},
 	}
 
@@ -511,3 +523,377 @@ func TestQueryJoining(t *testing.T) {
 		}
 	}
 }
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want func(o *options) // applied to defaultOptions()
+	}{
+		{
+			"defaults",
+			[]string{"how", "do", "I", "fork?"},
+			func(o *options) { o.query = "how do I fork?" },
+		},
+		{
+			"flags anywhere",
+			[]string{"what", "--sources", "is", "--version", "enterprise-cloud", "GHAS", "--wrap=80"},
+			func(o *options) {
+				o.query = "what is GHAS"
+				o.version = "enterprise-cloud"
+				o.showSources = true
+				o.wrapWidth = 80
+			},
+		},
+		{
+			"language",
+			[]string{"--language", "ja", "what", "is", "GHAS"},
+			func(o *options) { o.query = "what is GHAS"; o.language = "ja" },
+		},
+		{
+			"offline",
+			[]string{"--offline", "--docs-dir", "../docs", "what", "is", "GHAS"},
+			func(o *options) { o.query = "what is GHAS"; o.offline = true; o.docsDir = "../docs" },
+		},
+		{
+			"question from a file",
+			[]string{"-f", "question.md", "--editor"},
+			func(o *options) { o.file = "question.md"; o.editor = true },
+		},
+		{
+			"question from stdin",
+			[]string{"--sources", "-"},
+			func(o *options) { o.query = "-"; o.showSources = true },
+		},
+		{
+			"continue",
+			[]string{"--continue", "and", "for", "GHES?"},
+			func(o *options) { o.query = "and for GHES?"; o.continueConv = true },
+		},
+		{
+			"continue short flag",
+			[]string{"-c", "more"},
+			func(o *options) { o.query = "more"; o.continueConv = true },
+		},
+		{
+			"conversation id",
+			[]string{"--conversation", "conv-123", "follow", "up"},
+			func(o *options) { o.query = "follow up"; o.conversationID = "conv-123" },
+		},
+		{
+			"conversation id with equals",
+			[]string{"--conversation=conv-456", "follow", "up"},
+			func(o *options) { o.query = "follow up"; o.conversationID = "conv-456" },
+		},
+		{
+			"chat subcommand",
+			[]string{"chat", "--version", "enterprise-cloud"},
+			func(o *options) { o.version = "enterprise-cloud"; o.chat = true },
+		},
+		{
+			"interactive flag",
+			[]string{"-i"},
+			func(o *options) { o.chat = true },
+		},
+		{
+			"chat later in query is part of the question",
+			[]string{"what", "is", "chat"},
+			func(o *options) { o.query = "what is chat" },
+		},
+		{
+			"format",
+			[]string{"--format", "json", "q"},
+			func(o *options) { o.query = "q"; o.format = "json" },
+		},
+		{
+			"compare",
+			[]string{"--compare", "free-pro-team,enterprise-server@3.16", "--compare=enterprise-server@3.21", "q"},
+			func(o *options) {
+				o.query = "q"
+				o.compare = []string{"free-pro-team", "enterprise-server@3.16", "enterprise-server@3.21"}
+			},
+		},
+		{
+			"format with equals",
+			[]string{"--format=ndjson", "q"},
+			func(o *options) { o.query = "q"; o.format = "ndjson" },
+		},
+		{
+			"retries",
+			[]string{"--retries", "5", "--retry-backoff=2s", "--keep-partial", "q"},
+			func(o *options) {
+				o.query = "q"
+				o.retries = 5
+				o.retryBackoff = 2 * time.Second
+				o.keepPartial = true
+			},
+		},
+		{
+			"endpoint and headers",
+			[]string{"--endpoint", "http://localhost:8080/ai", "-H", "X-Token: abc", "--header=X-Trace:1", "--client-name=bot", "q"},
+			func(o *options) {
+				o.query = "q"
+				o.endpoint = "http://localhost:8080/ai"
+				o.headers = []string{"X-Token: abc", "X-Trace:1"}
+				o.clientName = "bot"
+			},
+		},
+		{
+			"ask subcommand",
+			[]string{"ask", "--sources", "how", "do", "I", "fork?"},
+			func(o *options) { o.query = "how do I fork?"; o.showSources = true },
+		},
+		{
+			"flags before subcommand",
+			[]string{"--version", "enterprise-cloud", "chat"},
+			func(o *options) { o.version = "enterprise-cloud"; o.chat = true },
+		},
+		{
+			"double dash ends flags",
+			[]string{"--sources", "--", "--force-with-lease", "vs", "--force"},
+			func(o *options) { o.query = "--force-with-lease vs --force"; o.showSources = true },
+		},
+		{
+			"double dash keeps command names in the query",
+			[]string{"--", "config", "for", "SAML"},
+			func(o *options) { o.query = "config for SAML" },
+		},
+		{
+			"timeouts",
+			[]string{"--timeout", "30s", "--idle-timeout=5s", "q"},
+			func(o *options) {
+				o.query = "q"
+				o.timeout = 30 * time.Second
+				o.idleTimeout = 5 * time.Second
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := defaultOptions()
+			tt.want(&want)
+			got, err := parseArgs(defaultOptions(), tt.args)
+			if err != nil {
+				t.Fatalf("parseArgs(%v) error: %v", tt.args, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("parseArgs(%v) = %+v, want %+v", tt.args, got, want)
+			}
+		})
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"unknown flag", []string{"--sourcs", "q"}, "unknown flag: --sourcs"},
+		{"unknown shorthand", []string{"-x", "q"}, "unknown shorthand flag: 'x'"},
+		{"non-numeric wrap", []string{"--wrap", "wide", "q"}, "invalid argument \"wide\" for \"--wrap\""},
+		{"invalid duration", []string{"--timeout=soon", "q"}, "--timeout"},
+		{"invalid theme", []string{"--theme", "blue", "q"}, "must be one of auto, light, dark"},
+		{"invalid format", []string{"--format=xml", "q"}, "must be one of text, json, ndjson"},
+		{"missing value", []string{"q", "--version"}, "flag needs an argument: --version"},
+		{"arguments to versions", []string{"versions", "extra"}, "unknown command \"extra\""},
+		{"question starting with cache", []string{"cache", "dependencies", "in", "actions"}, "unknown command \"dependencies\" for \"gh ask-docs cache\"\nTo ask a question starting with \"cache\", use: gh ask-docs -- cache dependencies in actions"},
+		{"question starting with export", []string{"export", "secrets", "to", "a", "workflow"}, "accepts 1 arg(s), received 4\nTo ask a question starting with \"export\", use: gh ask-docs -- export secrets to a workflow"},
+		{"question starting with a subcommand", []string{"history", "clear", "all", "entries"}, "use: gh ask-docs -- history clear all entries"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseArgs(defaultOptions(), tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseArgs(%v) error = %v, want it to contain %q", tt.args, err, tt.wantErr)
+			}
+			if askdocs.ExitCode(err) != askdocs.ExitError {
+				t.Errorf("ExitCode = %d, want %d", askdocs.ExitCode(err), askdocs.ExitError)
+			}
+		})
+	}
+}
+
+func TestParseArgsMissingQuery(t *testing.T) {
+	root := newRootCmd(defaultOptions(), runQuestion)
+	root.SetArgs([]string{"--sources"})
+	if err := root.Execute(); !errors.Is(err, errMissingQuery) {
+		t.Errorf("Execute() error = %v, want errMissingQuery", err)
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		root := newRootCmd(defaultOptions(), func(options) error { return nil })
+		var out bytes.Buffer
+		root.SetOut(&out)
+		root.SetArgs([]string{"completion", shell})
+		if err := root.Execute(); err != nil {
+			t.Errorf("completion %s error: %v", shell, err)
+		}
+		if !strings.Contains(out.String(), "gh-ask-docs") {
+			t.Errorf("completion %s script does not mention gh-ask-docs", shell)
+		}
+	}
+}
+
+// parseArgs runs the command tree on args and returns the options a question
+// would be asked with.
+func parseArgs(base options, args []string) (options, error) {
+	var got options
+	root := newRootCmd(base, func(opts options) error {
+		got = opts
+		return nil
+	})
+	root.SetArgs(args)
+	root.SetOut(io.Discard)
+	err := root.Execute()
+	return got, err
+}
+
+func TestNewClient(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		client, err := newClient(defaultOptions())
+		if err != nil {
+			t.Fatalf("newClient() error: %v", err)
+		}
+		if client.Endpoint != askdocs.DefaultEndpoint {
+			t.Errorf("Endpoint = %q, want %q", client.Endpoint, askdocs.DefaultEndpoint)
+		}
+		if client.ClientName != askdocs.DefaultClientName {
+			t.Errorf("ClientName = %q, want %q", client.ClientName, askdocs.DefaultClientName)
+		}
+		if client.Header != nil {
+			t.Errorf("Header = %v, want nil", client.Header)
+		}
+	})
+
+	t.Run("endpoint", func(t *testing.T) {
+		opts := defaultOptions()
+		opts.endpoint = "http://localhost:8080"
+		client, err := newClient(opts)
+		if err != nil {
+			t.Fatalf("newClient() error: %v", err)
+		}
+		if client.Endpoint != "http://localhost:8080" {
+			t.Errorf("Endpoint = %q", client.Endpoint)
+		}
+	})
+
+	t.Run("invalid endpoint", func(t *testing.T) {
+		for _, endpoint := range []string{"docs.github.com", "ftp://example.com", "http://"} {
+			opts := defaultOptions()
+			opts.endpoint = endpoint
+			if _, err := newClient(opts); err == nil {
+				t.Errorf("newClient() with endpoint %q should fail", endpoint)
+			}
+		}
+	})
+
+	t.Run("headers and client name", func(t *testing.T) {
+		opts := defaultOptions()
+		opts.headers = []string{"X-Token: abc", "X-Trace: 1", "X-Trace: 2"}
+		opts.clientName = "support-bot"
+		client, err := newClient(opts)
+		if err != nil {
+			t.Fatalf("newClient() error: %v", err)
+		}
+		if got := client.Header.Get("X-Token"); got != "abc" {
+			t.Errorf("X-Token = %q, want %q", got, "abc")
+		}
+		if got := client.Header.Values("X-Trace"); len(got) != 2 {
+			t.Errorf("X-Trace = %v, want two values", got)
+		}
+		if client.ClientName != "support-bot" {
+			t.Errorf("ClientName = %q, want %q", client.ClientName, "support-bot")
+		}
+	})
+
+	t.Run("invalid header", func(t *testing.T) {
+		opts := defaultOptions()
+		opts.headers = []string{"no-colon"}
+		if _, err := newClient(opts); err == nil {
+			t.Error("newClient() with an invalid header should fail")
+		}
+	})
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		in        string
+		key, val  string
+		wantError bool
+	}{
+		{"X-Token: abc", "X-Token", "abc", false},
+		{"X-Token:abc", "X-Token", "abc", false},
+		{"Authorization: Bearer a:b", "Authorization", "Bearer a:b", false},
+		{"X-Empty:", "X-Empty", "", false},
+		{"no-colon", "", "", true},
+		{": value", "", "", true},
+		{"Bad Key: value", "", "", true},
+	}
+
+	for _, tt := range tests {
+		key, val, err := parseHeader(tt.in)
+		if (err != nil) != tt.wantError {
+			t.Errorf("parseHeader(%q) error = %v, wantError %v", tt.in, err, tt.wantError)
+			continue
+		}
+		if key != tt.key || val != tt.val {
+			t.Errorf("parseHeader(%q) = %q, %q; want %q, %q", tt.in, key, val, tt.key, tt.val)
+		}
+	}
+}
+
+func TestDocsVersion(t *testing.T) {
+	opts := defaultOptions()
+
+	var got string
+	var err error
+	stderr := captureStderr(t, func() { got, err = docsVersion("enterprise-sever@3.17", opts) })
+	if err != nil || got != "free-pro-team@latest" {
+		t.Errorf("docsVersion() = %q, %v; want the free-pro-team fallback", got, err)
+	}
+	if !strings.Contains(stderr, `did you mean "enterprise-server@3.17"?`) || !strings.Contains(stderr, "using free-pro-team@latest") {
+		t.Errorf("warning = %q, want the suggestion and the substitute", stderr)
+	}
+
+	stderr = captureStderr(t, func() { got, err = docsVersion("enterprise-server@3.17", opts) })
+	if err != nil || got != "enterprise-server@3.17" || stderr != "" {
+		t.Errorf("docsVersion() = %q, %v, warning %q; want the version as given", got, err, stderr)
+	}
+
+	opts.strictVersion = true
+	if _, err := docsVersion("enterprise-server@3.9", opts); err == nil {
+		t.Error("docsVersion() with --strict-version should reject unsupported releases")
+	}
+}
+
+func TestResolveLanguage(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "ja_JP.UTF-8")
+
+	tests := []struct {
+		in, want string
+	}{
+		{languageAuto, "ja"},
+		{"pt-BR", "pt"},
+		{"EN", "en"},
+	}
+	for _, tt := range tests {
+		opts := defaultOptions()
+		opts.language = tt.in
+		if err := resolveLanguage(&opts); err != nil || opts.language != tt.want {
+			t.Errorf("resolveLanguage(%q) = %q, %v; want %q", tt.in, opts.language, err, tt.want)
+		}
+	}
+
+	opts := defaultOptions()
+	opts.language = "tlh"
+	if err := resolveLanguage(&opts); err == nil {
+		t.Error("resolveLanguage() should reject unsupported languages")
+	}
+}
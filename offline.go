@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// errNoDocsDir is returned when there is no github/docs checkout to index.
+var errNoDocsDir = errors.New("no github/docs checkout to index: pass its path or set docs-dir (gh ask-docs config set docs-dir <path>)")
+
+// offlineIndexPath returns where the offline search index is saved, under the
+// user cache directory.
+func offlineIndexPath() (string, error) {
+	dir, err := userCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gh-ask-docs", "offline-index.gob"), nil
+}
+
+// buildOfflineIndex indexes the github/docs checkout at dir and saves the
+// index for --offline.
+func buildOfflineIndex(dir string) (*askdocs.Index, error) {
+	if dir == "" {
+		return nil, errNoDocsDir
+	}
+	path, err := offlineIndexPath()
+	if err != nil {
+		return nil, err
+	}
+	ix, err := askdocs.BuildIndex(absPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	if err := ix.Save(path); err != nil {
+		return nil, fmt.Errorf("saving the offline index: %w", err)
+	}
+	return ix, nil
+}
+
+// loadOfflineIndex returns the saved offline index. It is built first when
+// there is none yet, it is from an older version, or docsDir names another
+// checkout than the one it was built from.
+func loadOfflineIndex(docsDir string) (*askdocs.Index, error) {
+	path, err := offlineIndexPath()
+	if err != nil {
+		return nil, err
+	}
+	ix, err := askdocs.LoadIndex(path)
+	switch {
+	case err == nil && (docsDir == "" || absPath(docsDir) == ix.DocsDir):
+		return ix, nil
+	case docsDir != "":
+		// (re)built below
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, errors.New("no offline index yet: run `gh ask-docs index <path to github/docs>` first")
+	default:
+		return nil, fmt.Errorf("%w; run `gh ask-docs index <path to github/docs>` to rebuild it", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Indexing %s for offline answers…\n", docsDir)
+	return buildOfflineIndex(docsDir)
+}
+
+// absPath returns dir as an absolute path, so an index built from a relative
+// path is found again from anywhere.
+func absPath(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// runIndex builds the offline index and reports what is in it.
+func runIndex(dir string) error {
+	start := time.Now()
+	ix, err := buildOfflineIndex(dir)
+	if err != nil {
+		return err
+	}
+	path, _ := offlineIndexPath()
+	fmt.Printf("Indexed %d articles from %s in %s\n", len(ix.Articles), ix.DocsDir, time.Since(start).Round(100*time.Millisecond))
+	fmt.Printf("Saved to %s; ask with --offline\n", path)
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDocsCheckout writes a github/docs checkout with two articles.
+func writeDocsCheckout(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "content", "pull-requests", "forks.md"),
+		"---\ntitle: About forks\nintro: A fork is a new repository that shares code with an upstream repository.\nversions:\n  fpt: '*'\n  ghec: '*'\n  ghes: '*'\n---\n\nFork a repository to propose changes.\n")
+	writeFile(t, filepath.Join(dir, "content", "admin", "fork-policy.md"),
+		"---\ntitle: Enforcing a fork policy\nversions:\n  ghes: '>=3.20'\n---\n\nRestrict who can fork repositories in your enterprise.\n")
+	return dir
+}
+
+func TestRunIndex(t *testing.T) {
+	withTempCacheDir(t)
+
+	if err := runIndex(""); err != errNoDocsDir {
+		t.Errorf("runIndex(\"\") error = %v, want errNoDocsDir", err)
+	}
+
+	dir := writeDocsCheckout(t)
+	out := captureStdout(t, func() {
+		if err := runIndex(dir); err != nil {
+			t.Errorf("runIndex() error: %v", err)
+		}
+	})
+	if !strings.HasPrefix(out, "Indexed 2 articles from "+dir) {
+		t.Errorf("output = %q", out)
+	}
+
+	ix, err := loadOfflineIndex("")
+	if err != nil {
+		t.Fatalf("loadOfflineIndex() error: %v", err)
+	}
+	if ix.DocsDir != dir {
+		t.Errorf("DocsDir = %q, want %q", ix.DocsDir, dir)
+	}
+}
+
+func TestLoadOfflineIndex(t *testing.T) {
+	withTempCacheDir(t)
+
+	if _, err := loadOfflineIndex(""); err == nil || !strings.Contains(err.Error(), "gh ask-docs index") {
+		t.Errorf("loadOfflineIndex() without an index error = %v, want a hint to build it", err)
+	}
+
+	first, second := writeDocsCheckout(t), writeDocsCheckout(t)
+	for _, dir := range []string{first, first, second} {
+		ix, err := loadOfflineIndex(dir)
+		if err != nil {
+			t.Fatalf("loadOfflineIndex(%q) error: %v", dir, err)
+		}
+		if ix.DocsDir != dir {
+			t.Errorf("DocsDir = %q, want the index rebuilt from %q", ix.DocsDir, dir)
+		}
+	}
+}
+
+func TestRunAskOffline(t *testing.T) {
+	withTempCacheDir(t)
+
+	opts := defaultOptions()
+	opts.query = "How do I fork a repository?"
+	opts.format = formatJSON
+	opts.offline = true
+	opts.docsDir = writeDocsCheckout(t)
+	opts.language = "en"
+
+	res := runAskJSON(t, opts)
+	if len(res.Sources) != 1 || res.Sources[0].URL != "https://docs.github.com/en/pull-requests/forks" {
+		t.Errorf("sources = %+v, want only the article published on free-pro-team", res.Sources)
+	}
+	if !strings.Contains(res.Answer, "Fork a repository to propose changes.") {
+		t.Errorf("answer = %q, want the article excerpt", res.Answer)
+	}
+
+	opts.version = "enterprise-server@3.20"
+	res = runAskJSON(t, opts)
+	if len(res.Sources) != 2 || res.Sources[1].URL != "https://docs.github.com/en/enterprise-server@3.20/admin/fork-policy" {
+		t.Errorf("sources = %+v, want both articles for enterprise-server@3.20", res.Sources)
+	}
+	if res.Cached {
+		t.Error("offline answers should not be cached")
+	}
+	if entries, _ := listCachedAnswers(); len(entries) != 0 {
+		t.Errorf("cached %d offline answers, want none", len(entries))
+	}
+}
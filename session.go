@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// userCacheDir is swapped out in tests.
+var userCacheDir = os.UserCacheDir
+
+// conversationState is the last conversation recorded for a shell session.
+type conversationState struct {
+	ConversationID string    `json:"conversation_id"`
+	Version        string    `json:"version"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+var unsafeKeyRe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// sessionEnv lists the environment variables that identify a terminal
+// session, in order of preference: GH_ASK_DOCS_SESSION, then macOS Terminal
+// and iTerm, Windows Terminal and tmux panes.
+var sessionEnv = []string{"GH_ASK_DOCS_SESSION", "TERM_SESSION_ID", "WT_SESSION", "TMUX_PANE"}
+
+// sessionKey identifies the current shell session so that --continue picks up
+// the conversation started in the same terminal. GH_ASK_DOCS_SESSION can be
+// set to share (or isolate) conversations explicitly. Terminals that set none
+// of sessionEnv are told apart by the tty of stdin.
+func sessionKey() string {
+	for _, env := range sessionEnv {
+		if v := os.Getenv(env); v != "" {
+			return unsafeKeyRe.ReplaceAllString(v, "_")
+		}
+	}
+	if tty := stdinTTY(); tty != "" {
+		return unsafeKeyRe.ReplaceAllString(strings.TrimPrefix(tty, "/dev/"), "_")
+	}
+	return "default"
+}
+
+// stdinTTY returns the name of the terminal on stdin, such as /dev/pts/3, or
+// "" when stdin is not a terminal or its name cannot be found. It is swapped
+// out in tests.
+var stdinTTY = func() string {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return ""
+	}
+	for _, link := range []string{"/proc/self/fd/0", "/dev/fd/0"} {
+		if name, err := os.Readlink(link); err == nil && strings.HasPrefix(name, "/dev/") {
+			return name
+		}
+	}
+	return ""
+}
+
+func conversationPath() (string, error) {
+	dir, err := userCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gh-ask-docs", "conversations", sessionKey()+".json"), nil
+}
+
+// loadConversation returns the last conversation saved for this session.
+func loadConversation() (*conversationState, error) {
+	path, err := conversationPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var state conversationState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if state.ConversationID == "" {
+		return nil, errors.New("saved conversation has no ID")
+	}
+	return &state, nil
+}
+
+// saveConversation records the conversation for this session.
+func saveConversation(state conversationState) error {
+	path, err := conversationPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	if state.UpdatedAt.IsZero() {
+		state.UpdatedAt = time.Now()
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
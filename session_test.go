@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// withTempCacheDir points the session store and answer cache at a temporary
+// directory, and the history at another.
+func withTempCacheDir(t *testing.T) string {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	orig := userCacheDir
+	userCacheDir = func() (string, error) { return dir, nil }
+	t.Cleanup(func() { userCacheDir = orig })
+	return dir
+}
+
+func TestSessionKey(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"default", map[string]string{}, "default"},
+		{"explicit session", map[string]string{"GH_ASK_DOCS_SESSION": "work"}, "work"},
+		{"terminal session", map[string]string{"TERM_SESSION_ID": "w0t0p0:ABC"}, "w0t0p0_ABC"},
+		{"explicit wins", map[string]string{"GH_ASK_DOCS_SESSION": "mine", "WT_SESSION": "other"}, "mine"},
+		{"tmux pane", map[string]string{"TMUX_PANE": "%3"}, "_3"},
+		{"tty", map[string]string{"tty": "/dev/pts/4"}, "pts_4"},
+		{"terminal before tty", map[string]string{"WT_SESSION": "wt", "tty": "/dev/pts/4"}, "wt"},
+		{"unsafe characters", map[string]string{"GH_ASK_DOCS_SESSION": "../../etc"}, ".._.._etc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range sessionEnv {
+				t.Setenv(env, tt.env[env])
+			}
+			orig := stdinTTY
+			stdinTTY = func() string { return tt.env["tty"] }
+			t.Cleanup(func() { stdinTTY = orig })
+			if got := sessionKey(); got != tt.want {
+				t.Errorf("sessionKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveAndLoadConversation(t *testing.T) {
+	withTempCacheDir(t)
+	t.Setenv("GH_ASK_DOCS_SESSION", "test")
+
+	if _, err := loadConversation(); err == nil {
+		t.Fatal("expected error when no conversation has been saved")
+	}
+
+	if err := saveConversation(conversationState{ConversationID: "conv-123", Version: "free-pro-team@latest"}); err != nil {
+		t.Fatalf("saveConversation() error: %v", err)
+	}
+
+	state, err := loadConversation()
+	if err != nil {
+		t.Fatalf("loadConversation() error: %v", err)
+	}
+	if state.ConversationID != "conv-123" {
+		t.Errorf("ConversationID = %q, want %q", state.ConversationID, "conv-123")
+	}
+	if state.Version != "free-pro-team@latest" {
+		t.Errorf("Version = %q, want %q", state.Version, "free-pro-team@latest")
+	}
+	if state.UpdatedAt.IsZero() {
+		t.Error("UpdatedAt should be set")
+	}
+
+	// A different session does not see the conversation.
+	t.Setenv("GH_ASK_DOCS_SESSION", "other")
+	if _, err := loadConversation(); err == nil {
+		t.Error("expected other session to have no conversation")
+	}
+}
+
+func TestResolveConversationIDVersion(t *testing.T) {
+	withTempCacheDir(t)
+	t.Setenv("GH_ASK_DOCS_SESSION", "test")
+	if err := saveConversation(conversationState{ConversationID: "conv-123", Version: "enterprise-server@3.18"}); err != nil {
+		t.Fatal(err)
+	}
+	opts := options{continueConv: true}
+
+	var id string
+	stderr := captureStderr(t, func() {
+		var err error
+		if id, err = resolveConversationID(opts, "enterprise-server@3.18"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if id != "conv-123" || stderr != "" {
+		t.Errorf("same version: id = %q, stderr = %q", id, stderr)
+	}
+
+	stderr = captureStderr(t, func() {
+		id, _ = resolveConversationID(opts, "free-pro-team@latest")
+	})
+	if id != "conv-123" || !strings.Contains(stderr, "about enterprise-server@3.18, but this question is for free-pro-team@latest") {
+		t.Errorf("other version: id = %q, stderr = %q", id, stderr)
+	}
+}
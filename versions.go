@@ -0,0 +1,86 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// enterpriseDatesURL lists the release and deprecation dates of every GHES
+// release; the update-enterprise-versions workflow reads the same file.
+const enterpriseDatesURL = "https://raw.githubusercontent.com/github/docs/refs/heads/main/src/ghes-releases/lib/enterprise-dates.json"
+
+// maxEnterpriseDatesSize bounds how much of the enterprise dates is read;
+// the real file is a few kilobytes.
+const maxEnterpriseDatesSize = 1 << 20
+
+// refreshedVersionsPath returns where `versions refresh` saves the supported
+// versions, under the user cache directory.
+func refreshedVersionsPath() (string, error) {
+	dir, err := userCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gh-ask-docs", "supported-versions.json"), nil
+}
+
+// refreshVersions fetches the enterprise dates from github/docs and saves the
+// supported versions worked out from them to askdocs.RefreshedVersionsPath.
+// GH_ASK_DOCS_ENTERPRISE_DATES_URL overrides where they are fetched from.
+func refreshVersions(ctx context.Context) (*askdocs.SupportedVersions, error) {
+	url := cmp.Or(os.Getenv("GH_ASK_DOCS_ENTERPRISE_DATES_URL"), enterpriseDatesURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", askdocs.ErrRequestFailed, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxEnterpriseDatesSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &askdocs.HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if len(raw) > maxEnterpriseDatesSize {
+		return nil, fmt.Errorf("enterprise dates from %s are larger than %d bytes", url, maxEnterpriseDatesSize)
+	}
+
+	versions, err := askdocs.SupportedVersionsFromDates(raw, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	path := askdocs.RefreshedVersionsPath
+	if path == "" {
+		return nil, fmt.Errorf("no cache directory to save the supported versions in")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return versions, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Ebonsignori/gh-ask-docs/askdocs"
+)
+
+// withRefreshedVersions points askdocs.RefreshedVersionsPath into a temp
+// cache directory and serves enterprise dates from body.
+func withRefreshedVersions(t *testing.T, status int, body string) {
+	t.Helper()
+	withTempCacheDir(t)
+	path, err := refreshedVersionsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := askdocs.RefreshedVersionsPath
+	t.Cleanup(func() { askdocs.RefreshedVersionsPath = orig })
+	askdocs.RefreshedVersionsPath = path
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("GH_ASK_DOCS_ENTERPRISE_DATES_URL", server.URL)
+}
+
+func TestRefreshVersions(t *testing.T) {
+	withRefreshedVersions(t, http.StatusOK, `{
+		"3.1":  {"releaseDate": "2021-05-20", "deprecationDate": "2022-06-03"},
+		"3.21": {"releaseDate": "2026-04-01", "deprecationDate": "2999-01-01"},
+		"3.22": {"releaseDate": "2026-07-01", "deprecationDate": "2999-01-01"},
+		"3.99": {"releaseDate": "2999-01-01", "deprecationDate": "3000-01-01"}
+	}`)
+
+	versions, err := refreshVersions(context.Background())
+	if err != nil {
+		t.Fatalf("refreshVersions() error: %v", err)
+	}
+	if strings.Join(versions.SupportedVersions, ",") != "3.21,3.22" || versions.LatestVersion != "3.22" {
+		t.Errorf("refreshVersions() = %+v, want 3.21 and 3.22", versions)
+	}
+
+	loaded, err := askdocs.LoadSupportedVersions()
+	if err != nil || !loaded.Refreshed || loaded.LatestVersion != "3.22" {
+		t.Errorf("LoadSupportedVersions() = %+v, %v; want the refreshed list", loaded, err)
+	}
+	if got := askdocs.NormalizeVersion("enterprise-server@3.22"); got != "enterprise-server@3.22" {
+		t.Errorf("NormalizeVersion() = %q, want the refreshed release", got)
+	}
+
+	out := captureStdout(t, func() {
+		if err := runVersions(defaultOptions()); err != nil {
+			t.Errorf("runVersions() error: %v", err)
+		}
+	})
+	if !strings.Contains(out, "enterprise-server@3.22") || !strings.Contains(out, "(latest)") || !strings.Contains(out, "refreshed from github/docs") {
+		t.Errorf("versions output = %q", out)
+	}
+}
+
+func TestRefreshVersionsError(t *testing.T) {
+	withRefreshedVersions(t, http.StatusNotFound, "404: Not Found")
+
+	if _, err := refreshVersions(context.Background()); askdocs.ExitCode(err) != askdocs.ExitHTTPClientError {
+		t.Errorf("refreshVersions() error = %v, want the HTTP status", err)
+	}
+	if _, err := os.Stat(askdocs.RefreshedVersionsPath); !os.IsNotExist(err) {
+		t.Error("a failed refresh should not write the cache")
+	}
+	if loaded, _ := askdocs.LoadSupportedVersions(); loaded.Refreshed {
+		t.Error("LoadSupportedVersions() should keep using the built-in list")
+	}
+}
+
+func TestRefreshVersionsTooLarge(t *testing.T) {
+	withRefreshedVersions(t, http.StatusOK, `{"3.21": {"releaseDate": "2026-04-01", "deprecationDate": "2999-01-01"}}`+strings.Repeat(" ", maxEnterpriseDatesSize))
+
+	if _, err := refreshVersions(context.Background()); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("refreshVersions() error = %v, want the size limit", err)
+	}
+	if _, err := os.Stat(askdocs.RefreshedVersionsPath); !os.IsNotExist(err) {
+		t.Error("an oversized response should not write the cache")
+	}
+}
+
+func TestRunVersionsJSON(t *testing.T) {
+	withRefreshedVersions(t, http.StatusOK, `{
+		"3.21": {"releaseDate": "2026-04-01", "deprecationDate": "2999-01-01"},
+		"3.22": {"releaseDate": "2026-07-01", "deprecationDate": "2999-01-01"}
+	}`)
+	if _, err := refreshVersions(context.Background()); err != nil {
+		t.Fatalf("refreshVersions() error: %v", err)
+	}
+
+	opts := defaultOptions()
+	opts.format = formatJSON
+	out := captureStdout(t, func() {
+		if err := runVersions(opts); err != nil {
+			t.Errorf("runVersions() error: %v", err)
+		}
+	})
+
+	var got struct {
+		Source   string                `json:"source"`
+		Versions []askdocs.VersionInfo `json:"versions"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if got.Source != "refreshed from github/docs" || len(got.Versions) != 4 {
+		t.Fatalf("versions = %+v", got)
+	}
+	if v := got.Versions[3]; v.Version != "enterprise-server@3.22" || v.ReleaseDate != "2026-07-01" || !v.Latest {
+		t.Errorf("latest release = %+v", v)
+	}
+}